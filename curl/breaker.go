@@ -0,0 +1,294 @@
+package curl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示熔断器处于打开状态，请求未发送，可用 errors.Is 判断。
+var ErrCircuitOpen = errors.New("http: circuit breaker is open")
+
+// BreakerState 表示熔断器状态。
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// CircuitOpenError 为熔断器拒绝请求时返回的错误，RetryAfter 为距离允许探测的剩余时间。
+type CircuitOpenError struct {
+	Key        string
+	State      BreakerState
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("http: circuit breaker for %s is %s, retry after %s", e.Key, e.State, e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerConfig 为熔断器配置，零值字段使用括号中的默认值。
+type BreakerConfig struct {
+	// MinRequests 为统计窗口内开始计算失败率的最少请求数（10）。
+	MinRequests int
+	// FailureRatio 为触发熔断的失败率，取值 0~1（0.5）。
+	FailureRatio float64
+	// Window 为关闭状态下的统计窗口，窗口结束后计数清零（60s）。
+	Window time.Duration
+	// Cooldown 为打开状态持续时间，之后进入半开状态允许探测请求（30s）。
+	Cooldown time.Duration
+	// HalfOpenProbes 为半开状态允许的探测请求数，全部成功后关闭熔断器，任一失败重新打开（1）。
+	HalfOpenProbes int
+	// PerEndpoint 为 true 时按主机加路径分别熔断，否则按主机熔断。
+	PerEndpoint bool
+	// IsFailure 判断一次请求是否失败，为空时传输错误和 5xx、429 响应视为失败。
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange 在状态变化后回调，key 为主机或主机加路径。
+	OnStateChange func(key string, from, to BreakerState)
+}
+
+// CircuitBreaker 按主机或接口熔断下游故障，通过 Setting.CircuitBreaker、Client.SetCircuitBreaker
+// 或 Request.SetCircuitBreaker 使用，可被多个客户端共享。熔断时请求直接返回 *CircuitOpenError，
+// 重试策略不会重试该错误。
+//
+//	breaker := curl.NewCircuitBreaker(curl.BreakerConfig{
+//	    Cooldown: 10 * time.Second,
+//	    OnStateChange: func(key string, from, to curl.BreakerState) {
+//	        log.Printf("breaker %s: %s -> %s", key, from, to)
+//	    },
+//	})
+//	client := curl.NewClient(payURL).SetCircuitBreaker(breaker)
+//	if _, err := client.Post("/pay").String(); errors.Is(err, curl.ErrCircuitOpen) {
+//	    ...
+//	}
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	breakers map[string]*breaker
+}
+
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.MinRequests <= 0 {
+		config.MinRequests = 10
+	}
+	if config.FailureRatio <= 0 {
+		config.FailureRatio = 0.5
+	}
+	if config.Window <= 0 {
+		config.Window = 60 * time.Second
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isBreakerFailure
+	}
+	return &CircuitBreaker{config: config, breakers: make(map[string]*breaker)}
+}
+
+// State 返回 key 对应熔断器的当前状态，key 为主机（如 api.example.com）或主机加路径。
+func (cb *CircuitBreaker) State(key string) BreakerState {
+	cb.mu.Lock()
+	br, ok := cb.breakers[key]
+	if !ok {
+		cb.mu.Unlock()
+		return BreakerClosed
+	}
+	transition := cb.refreshLocked(key, br, time.Now())
+	state := br.state
+	cb.mu.Unlock()
+	cb.notify(transition)
+	return state
+}
+
+func (b *Request) SetCircuitBreaker(breaker *CircuitBreaker) *Request {
+	b.setting.CircuitBreaker = breaker
+	return b
+}
+
+func (c *Client) SetCircuitBreaker(breaker *CircuitBreaker) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.CircuitBreaker = breaker
+	return c
+}
+
+// breaker 为单个主机或接口的熔断状态，generation 用于忽略状态切换前发出的请求结果。
+type breaker struct {
+	state      BreakerState
+	generation uint64
+	expiry     time.Time
+	requests   int
+	failures   int
+	probes     int
+	successes  int
+}
+
+type breakerTransition struct {
+	key      string
+	from, to BreakerState
+}
+
+func (cb *CircuitBreaker) wrap(next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		key := req.URL.Host
+		if cb.config.PerEndpoint {
+			key += req.URL.Path
+		}
+		generation, err := cb.allow(key)
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+		resp, err := next(req)
+		if errors.Is(err, context.Canceled) {
+			// 调用方主动取消的请求不计入统计，只归还半开状态的探测名额。
+			cb.cancel(key, generation)
+			return resp, err
+		}
+		cb.done(key, generation, cb.config.IsFailure(resp, err))
+		return resp, err
+	}
+}
+
+func (cb *CircuitBreaker) allow(key string) (uint64, error) {
+	cb.mu.Lock()
+	now := time.Now()
+	br, ok := cb.breakers[key]
+	if !ok {
+		br = &breaker{expiry: now.Add(cb.config.Window)}
+		cb.breakers[key] = br
+	}
+	transition := cb.refreshLocked(key, br, now)
+	var err error
+	switch br.state {
+	case BreakerOpen:
+		err = &CircuitOpenError{Key: key, State: br.state, RetryAfter: br.expiry.Sub(now)}
+	case BreakerHalfOpen:
+		if br.probes >= cb.config.HalfOpenProbes {
+			err = &CircuitOpenError{Key: key, State: br.state}
+		} else {
+			br.probes++
+		}
+	default:
+		br.requests++
+	}
+	generation := br.generation
+	cb.mu.Unlock()
+	cb.notify(transition)
+	return generation, err
+}
+
+func (cb *CircuitBreaker) done(key string, generation uint64, failed bool) {
+	cb.mu.Lock()
+	now := time.Now()
+	br := cb.breakers[key]
+	var transition *breakerTransition
+	if br != nil && br.generation == generation {
+		switch br.state {
+		case BreakerClosed:
+			if failed {
+				br.failures++
+				if br.requests >= cb.config.MinRequests &&
+					float64(br.failures)/float64(br.requests) >= cb.config.FailureRatio {
+					transition = cb.setStateLocked(key, br, BreakerOpen, now)
+				}
+			}
+		case BreakerHalfOpen:
+			if failed {
+				transition = cb.setStateLocked(key, br, BreakerOpen, now)
+				break
+			}
+			br.successes++
+			if br.successes >= cb.config.HalfOpenProbes {
+				transition = cb.setStateLocked(key, br, BreakerClosed, now)
+			}
+		}
+	}
+	cb.mu.Unlock()
+	cb.notify(transition)
+}
+
+func (cb *CircuitBreaker) cancel(key string, generation uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	br := cb.breakers[key]
+	if br == nil || br.generation != generation {
+		return
+	}
+	switch br.state {
+	case BreakerClosed:
+		br.requests--
+	case BreakerHalfOpen:
+		br.probes--
+	}
+}
+
+// refreshLocked 处理打开状态冷却结束和关闭状态统计窗口到期，调用方需持有 cb.mu。
+func (cb *CircuitBreaker) refreshLocked(key string, br *breaker, now time.Time) *breakerTransition {
+	if now.Before(br.expiry) {
+		return nil
+	}
+	switch br.state {
+	case BreakerOpen:
+		return cb.setStateLocked(key, br, BreakerHalfOpen, now)
+	case BreakerClosed:
+		br.generation++
+		br.requests, br.failures = 0, 0
+		br.expiry = now.Add(cb.config.Window)
+	}
+	return nil
+}
+
+func (cb *CircuitBreaker) setStateLocked(key string, br *breaker, state BreakerState, now time.Time) *breakerTransition {
+	transition := &breakerTransition{key: key, from: br.state, to: state}
+	br.state = state
+	br.generation++
+	br.requests, br.failures, br.probes, br.successes = 0, 0, 0, 0
+	switch state {
+	case BreakerClosed:
+		br.expiry = now.Add(cb.config.Window)
+	case BreakerOpen:
+		br.expiry = now.Add(cb.config.Cooldown)
+	default:
+		br.expiry = time.Time{}
+	}
+	return transition
+}
+
+func (cb *CircuitBreaker) notify(transition *breakerTransition) {
+	if transition != nil && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(transition.key, transition.from, transition.to)
+	}
+}
+
+func isBreakerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,204 @@
+package curl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// breakerStep 为熔断器状态机测试中的一步：发送一次成功或失败的请求，或等待冷却结束。
+type breakerStep struct {
+	action  string
+	wantErr bool
+	want    BreakerState
+}
+
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	tests := []struct {
+		name        string
+		config      BreakerConfig
+		steps       []breakerStep
+		transitions []string
+	}{
+		{
+			"below min requests",
+			BreakerConfig{MinRequests: 3},
+			[]breakerStep{
+				{"fail", false, BreakerClosed},
+				{"fail", false, BreakerClosed},
+			},
+			nil,
+		},
+		{
+			"opens on failure ratio",
+			BreakerConfig{MinRequests: 4, FailureRatio: 0.5},
+			[]breakerStep{
+				{"ok", false, BreakerClosed},
+				{"ok", false, BreakerClosed},
+				{"fail", false, BreakerClosed},
+				{"fail", false, BreakerOpen},
+				{"ok", true, BreakerOpen},
+			},
+			[]string{"closed->open"},
+		},
+		{
+			"half-open probe closes",
+			BreakerConfig{MinRequests: 1, Cooldown: cooldown},
+			[]breakerStep{
+				{"fail", false, BreakerOpen},
+				{"wait", false, BreakerHalfOpen},
+				{"ok", false, BreakerClosed},
+				{"ok", false, BreakerClosed},
+			},
+			[]string{"closed->open", "open->half-open", "half-open->closed"},
+		},
+		{
+			"half-open probe reopens",
+			BreakerConfig{MinRequests: 1, Cooldown: cooldown},
+			[]breakerStep{
+				{"fail", false, BreakerOpen},
+				{"wait", false, BreakerHalfOpen},
+				{"fail", false, BreakerOpen},
+				{"ok", true, BreakerOpen},
+			},
+			[]string{"closed->open", "open->half-open", "half-open->open"},
+		},
+		{
+			"several probes",
+			BreakerConfig{MinRequests: 1, Cooldown: cooldown, HalfOpenProbes: 2},
+			[]breakerStep{
+				{"fail", false, BreakerOpen},
+				{"wait", false, BreakerHalfOpen},
+				{"ok", false, BreakerHalfOpen},
+				{"ok", false, BreakerClosed},
+			},
+			[]string{"closed->open", "open->half-open", "half-open->closed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var transitions []string
+			config := tt.config
+			config.OnStateChange = func(key string, from, to BreakerState) {
+				if key != "api.example.com" {
+					t.Errorf("OnStateChange key = %q, want api.example.com", key)
+				}
+				transitions = append(transitions, from.String()+"->"+to.String())
+			}
+			cb := NewCircuitBreaker(config)
+			for i, step := range tt.steps {
+				var err error
+				switch step.action {
+				case "wait":
+					time.Sleep(cooldown + 10*time.Millisecond)
+				case "ok":
+					_, err = cb.wrap(statusHandler(http.StatusOK))(newBreakerRequest(t, "/pay"))
+				case "fail":
+					_, err = cb.wrap(statusHandler(http.StatusBadGateway))(newBreakerRequest(t, "/pay"))
+				}
+				if gotErr := errors.Is(err, ErrCircuitOpen); gotErr != step.wantErr {
+					t.Fatalf("step %d (%s): error = %v, want circuit open %v", i, step.action, err, step.wantErr)
+				}
+				if got := cb.State("api.example.com"); got != step.want {
+					t.Fatalf("step %d (%s): state = %s, want %s", i, step.action, got, step.want)
+				}
+			}
+			if !reflect.DeepEqual(transitions, tt.transitions) {
+				t.Errorf("transitions = %q, want %q", transitions, tt.transitions)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleAndCanceled(t *testing.T) {
+	cb := NewCircuitBreaker(BreakerConfig{MinRequests: 1, Cooldown: 10 * time.Millisecond})
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cb.wrap(func(*http.Request) (*http.Response, error) {
+			close(started)
+			<-release
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})(newBreakerRequest(t, "/slow"))
+	}()
+	<-started
+	_, _ = cb.wrap(statusHandler(http.StatusInternalServerError))(newBreakerRequest(t, "/fail"))
+	time.Sleep(20 * time.Millisecond)
+	if got := cb.State("api.example.com"); got != BreakerHalfOpen {
+		t.Fatalf("state after cooldown = %s, want half-open", got)
+	}
+	close(release)
+	<-done
+	if got := cb.State("api.example.com"); got != BreakerHalfOpen {
+		t.Errorf("state after a stale success = %s, want open", got)
+	}
+
+	cb = NewCircuitBreaker(BreakerConfig{MinRequests: 1})
+	canceled := func(*http.Request) (*http.Response, error) { return nil, context.Canceled }
+	if _, err := cb.wrap(canceled)(newBreakerRequest(t, "/")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if got := cb.State("api.example.com"); got != BreakerClosed {
+		t.Errorf("state after a canceled request = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakerPerEndpoint(t *testing.T) {
+	cb := NewCircuitBreaker(BreakerConfig{MinRequests: 1, PerEndpoint: true})
+	_, _ = cb.wrap(statusHandler(http.StatusServiceUnavailable))(newBreakerRequest(t, "/a"))
+	tests := []struct {
+		key  string
+		want BreakerState
+	}{
+		{"api.example.com/a", BreakerOpen},
+		{"api.example.com/b", BreakerClosed},
+		{"api.example.com", BreakerClosed},
+	}
+	for _, tt := range tests {
+		if got := cb.State(tt.key); got != tt.want {
+			t.Errorf("State(%q) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestIsBreakerFailure(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{0, errors.New("dial tcp: refused"), true},
+		{http.StatusOK, nil, false},
+		{http.StatusNotFound, nil, false},
+		{http.StatusTooManyRequests, nil, true},
+		{http.StatusInternalServerError, nil, true},
+	}
+	for _, tt := range tests {
+		var resp *http.Response
+		if tt.err == nil {
+			resp = &http.Response{StatusCode: tt.status}
+		}
+		if got := isBreakerFailure(resp, tt.err); got != tt.want {
+			t.Errorf("isBreakerFailure(%d, %v) = %v, want %v", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func statusHandler(status int) Handler {
+	return func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}
+}
+
+func newBreakerRequest(t *testing.T, path string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest("GET", "http://api.example.com"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
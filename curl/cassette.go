@@ -0,0 +1,322 @@
+package curl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode 表示 Cassette 的录制回放模式。
+type CassetteMode int
+
+const (
+	// CassetteReplay 只从文件回放，没有匹配的记录时返回错误，不会访问网络。
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord 总是发送真实请求，并从空记录开始重新录制，写入时会覆盖文件中已有的记录。
+	CassetteRecord
+	// CassetteReplayOrRecord 优先回放，没有匹配的记录时发送真实请求并录制。
+	CassetteReplayOrRecord
+)
+
+// redactedValue 为脱敏后写入文件的值。
+const redactedValue = "REDACTED"
+
+// CassetteOptions 为 NewCassette 的配置。
+//
+// 请求总是按方法和 URL 匹配，MatchBody 和 MatchHeaders 用于进一步区分同一地址的请求。
+// 脱敏在写入文件和匹配前进行，因此录制和回放时密钥不同也能匹配。
+type CassetteOptions struct {
+	Mode CassetteMode
+	// MatchBody 为 true 时要求请求体完全一致。
+	MatchBody bool
+	// MatchHeaders 为需要一致的请求头。
+	MatchHeaders []string
+	// RedactHeaders 为需要脱敏的请求头和响应头，Authorization、Cookie、Set-Cookie 总会脱敏。
+	RedactHeaders []string
+	// RedactQuery 为需要脱敏的 URL 查询参数，如 access_token。
+	RedactQuery []string
+	// RedactBody 在保存前处理请求体和响应体，可用于替换其中的密钥。
+	RedactBody func(body []byte) []byte
+	// Transport 为录制时发送真实请求使用的 RoundTripper，为空时使用 http.DefaultTransport。
+	Transport http.RoundTripper
+}
+
+// Cassette 是可录制和回放 HTTP 请求的 http.RoundTripper，通过 Setting.Transport 或 SetTransport 使用。
+//
+//	cassette, err := curl.NewCassette("testdata/wechat_token.json", curl.CassetteOptions{
+//	    Mode:        curl.CassetteReplayOrRecord,
+//	    RedactQuery: []string{"secret", "access_token"},
+//	})
+//	setting := curl.Setting{Transport: cassette}
+//	body, err := curl.Get(tokenURL).Setting(setting).String()
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	opts         CassetteOptions
+	interactions []*cassetteInteraction
+	used         []bool
+}
+
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"`
+}
+
+// NewCassette 加载 path 中已录制的请求，文件不存在时从空记录开始，回放模式下文件必须存在。
+// 录制模式不加载已有记录，重新录制会替换整个文件。
+func NewCassette(path string, opts CassetteOptions) (*Cassette, error) {
+	c := &Cassette{path: path, opts: opts}
+	if opts.Mode == CassetteRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && opts.Mode != CassetteReplay {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("http: invalid cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	record := c.redactRequest(req, body)
+	if c.opts.Mode != CassetteRecord {
+		if resp, ok := c.replay(req, record); ok {
+			return resp, nil
+		}
+		if c.opts.Mode == CassetteReplay {
+			return nil, fmt.Errorf("http: no cassette interaction matches %s %s", record.Method, record.URL)
+		}
+	}
+	return c.record(req, body, record)
+}
+
+func (c *Cassette) replay(req *http.Request, record cassetteRequest) (*http.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 相同请求按录制顺序依次回放，全部回放过后重复使用最后一条。
+	last := -1
+	for i, interaction := range c.interactions {
+		if !c.match(interaction.Request, record) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction.Response.toResponse(req), true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return c.interactions[last].Response.toResponse(req), true
+	}
+	return nil, false
+}
+
+func (c *Cassette) record(req *http.Request, body []byte, record cassetteRequest) (*http.Response, error) {
+	transport := c.opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	interaction := &cassetteInteraction{
+		Request: record,
+		Response: cassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     c.redactHeader(resp.Header),
+		},
+	}
+	interaction.Response.Body, interaction.Response.Encoding = encodeCassetteBody(c.redactBody(respBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	if err := c.saveLocked(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Save 把当前记录写入文件，录制模式下每次录制后都会自动保存。
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+func (c *Cassette) saveLocked() error {
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+func (c *Cassette) match(recorded, req cassetteRequest) bool {
+	if recorded.Method != req.Method || c.normalizeURL(recorded.URL) != req.URL {
+		return false
+	}
+	if c.opts.MatchBody && (recorded.Body != req.Body || recorded.Encoding != req.Encoding) {
+		return false
+	}
+	for _, key := range c.opts.MatchHeaders {
+		if fmt.Sprint(recorded.Header.Values(key)) != fmt.Sprint(req.Header.Values(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeURL 规范化录制文件中的 URL，兼容手工编辑或旧版本录制的未排序查询串。
+func (c *Cassette) normalizeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return c.redactURL(u)
+}
+
+func (c *Cassette) redactRequest(req *http.Request, body []byte) cassetteRequest {
+	record := cassetteRequest{
+		Method: req.Method,
+		URL:    c.redactURL(req.URL),
+		Header: c.redactHeader(req.Header),
+	}
+	record.Body, record.Encoding = encodeCassetteBody(c.redactBody(body))
+	return record
+}
+
+// redactURL 按参数名排序查询串并脱敏，使参数顺序不同的同一请求也能匹配。
+func (c *Cassette) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	query := u.Query()
+	for _, key := range c.opts.RedactQuery {
+		if query.Has(key) {
+			query.Set(key, redactedValue)
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+func (c *Cassette) redactHeader(header http.Header) http.Header {
+	out := header.Clone()
+	for _, key := range append([]string{"Authorization", "Cookie", "Set-Cookie"}, c.opts.RedactHeaders...) {
+		if values := out.Values(key); len(values) > 0 {
+			out.Del(key)
+			for range values {
+				out.Add(key, redactedValue)
+			}
+		}
+	}
+	return out
+}
+
+func (c *Cassette) redactBody(body []byte) []byte {
+	if c.opts.RedactBody == nil || len(body) == 0 {
+		return body
+	}
+	return c.opts.RedactBody(body)
+}
+
+func (r cassetteResponse) toResponse(req *http.Request) *http.Response {
+	body, err := decodeCassetteBody(r.Body, r.Encoding)
+	if err != nil {
+		body = []byte(r.Body)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// encodeCassetteBody 保存文本原文，非 UTF-8 内容使用 base64。
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return nil, errors.New("http: unknown cassette body encoding " + encoding)
+}
@@ -0,0 +1,117 @@
+package curl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = fmt.Fprintf(w, "%s call %d", r.URL.Path, calls)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	opts := CassetteOptions{RedactQuery: []string{"secret"}}
+
+	opts.Mode = CassetteRecord
+	recorder, err := NewCassette(path, opts)
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Get(srv.URL+"/token?secret=s1&appid=a").SetTransport(recorder).String(); err != nil {
+			t.Fatalf("record request %d error: %v", i, err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{"s1", "session=abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette file contains %q", secret)
+		}
+	}
+
+	opts.Mode = CassetteReplay
+	player, err := NewCassette(path, opts)
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	tests := []struct {
+		url  string
+		want string
+		err  bool
+	}{
+		{"/token?appid=a&secret=s2", "/token call 1", false},
+		{"/token?secret=s3&appid=a", "/token call 2", false},
+		{"/token?secret=s4&appid=a", "/token call 2", false},
+		{"/other", "", true},
+	}
+	for _, tt := range tests {
+		body, err := Get(srv.URL + tt.url).SetTransport(player).String()
+		if (err != nil) != tt.err || body != tt.want {
+			t.Errorf("replay %s = %q, %v; want %q, error %v", tt.url, body, err, tt.want, tt.err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("server received %d requests, want 2", calls)
+	}
+
+	opts.Mode = CassetteRecord
+	rerecord, err := NewCassette(path, opts)
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	if _, err := Get(srv.URL + "/token?secret=s5&appid=a").SetTransport(rerecord).String(); err != nil {
+		t.Fatalf("re-record error: %v", err)
+	}
+	opts.Mode = CassetteReplay
+	player, _ = NewCassette(path, opts)
+	if got := len(player.interactions); got != 1 {
+		t.Errorf("re-recorded cassette has %d interactions, want 1", got)
+	}
+}
+
+func TestCassetteBodyEncoding(t *testing.T) {
+	tests := []struct {
+		body     []byte
+		encoding string
+	}{
+		{nil, ""},
+		{[]byte(`{"a":1}`), ""},
+		{[]byte{0xff, 0x00, 0xfe}, "base64"},
+	}
+	for _, tt := range tests {
+		encoded, encoding := encodeCassetteBody(tt.body)
+		if encoding != tt.encoding {
+			t.Errorf("encodeCassetteBody(%q) encoding = %q, want %q", tt.body, encoding, tt.encoding)
+		}
+		decoded, err := decodeCassetteBody(encoded, encoding)
+		if err != nil || string(decoded) != string(tt.body) {
+			t.Errorf("decodeCassetteBody(encodeCassetteBody(%q)) = %q, %v", tt.body, decoded, err)
+		}
+	}
+}
+
+func TestNewCassetteMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	tests := []struct {
+		mode    CassetteMode
+		wantErr bool
+	}{
+		{CassetteReplay, true},
+		{CassetteRecord, false},
+		{CassetteReplayOrRecord, false},
+	}
+	for _, tt := range tests {
+		if _, err := NewCassette(path, CassetteOptions{Mode: tt.mode}); (err != nil) != tt.wantErr {
+			t.Errorf("NewCassette(mode %d) error = %v, want error %v", tt.mode, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package curl
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client 持有共享的 keep-alive 连接池、默认请求头、基础地址和 cookie jar，
+// 由它创建的 Request 会复用连接，可被多个 goroutine 并发使用。
+// 请求上调用 SetTimeout、SetTLSClientConfig、SetProxy，或通过 Setting 修改这些连接设置后，
+// 该请求会使用按自身设置创建的独立连接，不复用客户端连接池。
+//
+//	client := curl.NewClient("https://api.example.com/v1").SetHeader("Authorization", "Bearer xxx")
+//	var out Result
+//	err := client.Get("/users").Param("page", "1").ToJSON(&out)
+type Client struct {
+	mu                  sync.RWMutex
+	baseURL             string
+	header              http.Header
+	setting             Setting
+	jar                 http.CookieJar
+	transport           *http.Transport
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	middlewares         []Middleware
+}
+
+func NewClient(baseURL string) *Client {
+	settingMutex.Lock()
+	setting := defaultSetting
+	settingMutex.Unlock()
+	return &Client{
+		baseURL:             baseURL,
+		header:              make(http.Header),
+		setting:             setting,
+		maxIdleConns:        100,
+		maxIdleConnsPerHost: 100,
+		idleConnTimeout:     90 * time.Second,
+	}
+}
+
+func (c *Client) SetBaseURL(baseURL string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseURL = baseURL
+	return c
+}
+
+func (c *Client) SetHeader(key, value string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header.Set(key, value)
+	return c
+}
+
+// Setting 替换客户端的默认设置，连接相关的设置会在下一次请求时重建连接池。
+func (c *Client) Setting(setting Setting) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting = setting
+	c.resetTransportLocked()
+	return c
+}
+
+func (c *Client) SetTimeout(connectTimeout, readWriteTimeout time.Duration) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.ConnectTimeout = connectTimeout
+	c.setting.ReadWriteTimeout = readWriteTimeout
+	c.resetTransportLocked()
+	return c
+}
+
+func (c *Client) SetTLSClientConfig(config *tls.Config) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.TLSClientConfig = config
+	c.resetTransportLocked()
+	return c
+}
+
+func (c *Client) SetProxy(proxy func(*http.Request) (*url.URL, error)) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.Proxy = proxy
+	c.resetTransportLocked()
+	return c
+}
+
+// SetPool 设置连接池上限，maxConnsPerHost 为 0 表示不限制单个主机的连接数。
+func (c *Client) SetPool(maxIdleConns, maxIdleConnsPerHost, maxConnsPerHost int, idleConnTimeout time.Duration) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxIdleConns = maxIdleConns
+	c.maxIdleConnsPerHost = maxIdleConnsPerHost
+	c.maxConnsPerHost = maxConnsPerHost
+	c.idleConnTimeout = idleConnTimeout
+	c.resetTransportLocked()
+	return c
+}
+
+// SetCookieJar 设置客户端使用的 cookie jar，设置后所有请求都会携带和保存 cookie。
+func (c *Client) SetCookieJar(jar http.CookieJar) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jar = jar
+	return c
+}
+
+// SetEnableCookie 开启后客户端使用独立的 cookie jar，不与包级请求共享。
+func (c *Client) SetEnableCookie(enable bool) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.EnableCookie = enable
+	return c
+}
+
+func (c *Client) CloseIdleConnections() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+}
+
+// NewRequest 创建绑定到客户端的请求，path 为相对地址时拼接在基础地址之后，
+// 请求会继承客户端的设置和默认请求头，之后对请求的修改不会影响客户端。
+func (c *Client) NewRequest(path, method string) *Request {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	b := NewRequest(c.resolveURL(path), method)
+	b.setting = c.setting
+	for k, v := range c.header {
+		b.req.Header[k] = append([]string(nil), v...)
+	}
+	b.middlewares = append([]Middleware(nil), c.middlewares...)
+	b.client = c
+	return b
+}
+
+func (c *Client) Get(path string) *Request {
+	return c.NewRequest(path, "GET")
+}
+
+func (c *Client) Post(path string) *Request {
+	return c.NewRequest(path, "POST")
+}
+
+func (c *Client) Put(path string) *Request {
+	return c.NewRequest(path, "PUT")
+}
+
+func (c *Client) Delete(path string) *Request {
+	return c.NewRequest(path, "DELETE")
+}
+
+func (c *Client) Head(path string) *Request {
+	return c.NewRequest(path, "HEAD")
+}
+
+func (c *Client) resolveURL(path string) string {
+	if c.baseURL == "" || strings.Contains(path, "://") {
+		return path
+	}
+	if path == "" {
+		return c.baseURL
+	}
+	if strings.HasPrefix(path, "?") {
+		return c.baseURL + path
+	}
+	return strings.TrimRight(c.baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+func (c *Client) getTransport() *http.Transport {
+	c.mu.RLock()
+	trans := c.transport
+	c.mu.RUnlock()
+	if trans != nil {
+		return trans
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transport == nil {
+		c.transport = newTransport(c.setting, true)
+		c.transport.MaxIdleConns = c.maxIdleConns
+		c.transport.MaxIdleConnsPerHost = c.maxIdleConnsPerHost
+		c.transport.MaxConnsPerHost = c.maxConnsPerHost
+		c.transport.IdleConnTimeout = c.idleConnTimeout
+	}
+	return c.transport
+}
+
+func (c *Client) getCookieJar(enable bool) http.CookieJar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.jar == nil && (enable || c.setting.EnableCookie) {
+		c.jar, _ = cookiejar.New(nil)
+	}
+	return c.jar
+}
+
+func (c *Client) resetTransportLocked() {
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+		c.transport = nil
+	}
+}
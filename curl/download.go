@@ -0,0 +1,457 @@
+package curl
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minSegmentSize 为并行下载时每个分段的最小字节数，文件较小时不会拆分。
+const minSegmentSize = 1 << 20
+
+// metaSaveInterval 为下载过程中保存断点续传信息的间隔字节数。
+const metaSaveInterval = 4 << 20
+
+// DownloadOptions 为 Download 的可选配置。
+type DownloadOptions struct {
+	// Segments 为并行下载的分段数，小于等于 1 时单连接下载；服务端不支持 Range、
+	// 没有返回强 ETag 或 Last-Modified 或请求不是 GET 时忽略。
+	Segments int
+	// Size 为期望的文件大小，大于 0 时下载完成后校验。
+	Size int64
+	// MD5 为期望的 MD5 十六进制摘要，不为空时下载完成后校验。
+	MD5 string
+	// SHA256 为期望的 SHA-256 十六进制摘要，不为空时下载完成后校验。
+	SHA256 string
+	// Progress 在写入数据后回调已下载字节数和总字节数，总字节数未知时为 -1，回调不会被并发调用。
+	Progress func(downloaded, total int64)
+}
+
+// Download 把响应体下载到 filename，先写入 filename.download 临时文件，校验通过后原子重命名。
+//
+// 临时文件存在且服务端返回了强 ETag 或 Last-Modified 时，再次调用会通过 Range 和 If-Range 断点续传，
+// 文件已在服务端变化时自动从头下载。下载进度在下载过程中定期保存到 filename.download.meta，
+// 进程异常退出后也能续传。
+// 大小或摘要校验失败时会删除临时文件并返回错误；服务端返回非 2xx 时返回 *HTTPError。
+// 下载不受 ReadWriteTimeout 的整体超时限制，需要通过 WithContext 控制取消。
+//
+//	err := curl.Get(modelURL).WithContext(ctx).Download("/data/model.bin", curl.DownloadOptions{
+//	    Segments: 4,
+//	    SHA256:   "9f86d0...",
+//	    Progress: func(n, total int64) { log.Printf("%d/%d", n, total) },
+//	})
+func (b *Request) Download(filename string, opts DownloadOptions) (err error) {
+	temp := filename + ".download"
+	metaPath := temp + ".meta"
+	state := loadDownloadState(temp, metaPath)
+	f, err := os.OpenFile(temp, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	if state == nil {
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err != nil && state != nil {
+			state.save(metaPath)
+		}
+	}()
+	d := &downloader{req: b, file: f, state: state, metaPath: metaPath, progress: opts.Progress}
+	err = d.start(opts.Segments)
+	state = d.state
+	if err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			state = nil
+			_ = os.Remove(metaPath)
+			_ = f.Truncate(0)
+		}
+		return err
+	}
+	if err := verifyDownload(f, state.Size, opts); err != nil {
+		state = nil
+		_ = f.Close()
+		_ = os.Remove(temp)
+		_ = os.Remove(metaPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, filename); err != nil {
+		return err
+	}
+	_ = os.Remove(metaPath)
+	return nil
+}
+
+// downloadState 为断点续传信息，End 为 -1 表示总大小未知。
+type downloadState struct {
+	Validator string             `json:"validator"`
+	Size      int64              `json:"size"`
+	Segments  []*downloadSegment `json:"segments"`
+}
+
+type downloadSegment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+func (s *downloadSegment) next() int64 {
+	return s.Start + s.Done
+}
+
+func (s *downloadSegment) complete() bool {
+	return s.End >= 0 && s.next() > s.End
+}
+
+func (s *downloadSegment) rangeHeader() string {
+	if s.End < 0 {
+		return fmt.Sprintf("bytes=%d-", s.next())
+	}
+	return fmt.Sprintf("bytes=%d-%d", s.next(), s.End)
+}
+
+func loadDownloadState(temp, metaPath string) *downloadState {
+	info, err := os.Stat(temp)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil || state.Validator == "" || len(state.Segments) == 0 {
+		return nil
+	}
+	if len(state.Segments) == 1 {
+		// 单连接下载按顺序写入，临时文件大小就是已下载的字节数。
+		state.Segments[0].Done = info.Size()
+	}
+	return &state
+}
+
+func (s *downloadState) save(metaPath string) {
+	if s.Validator == "" {
+		_ = os.Remove(metaPath)
+		return
+	}
+	data, err := json.Marshal(s)
+	if err == nil {
+		_ = os.WriteFile(metaPath, data, 0644)
+	}
+}
+
+func (s *downloadState) pending() []*downloadSegment {
+	var segments []*downloadSegment
+	for _, seg := range s.Segments {
+		if !seg.complete() {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+func (s *downloadState) done() int64 {
+	var done int64
+	for _, seg := range s.Segments {
+		done += seg.Done
+	}
+	return done
+}
+
+type downloader struct {
+	req      *Request
+	file     *os.File
+	state    *downloadState
+	metaPath string
+	progress func(downloaded, total int64)
+	mu       sync.Mutex
+	written  int64
+	saved    int64
+}
+
+// start 发送首个请求，根据响应确定续传、重新下载或分段下载，并等待所有分段完成。
+func (d *downloader) start(segments int) error {
+	b := d.req
+	b.stream = true
+	b.req.Header.Set("Accept-Encoding", "identity")
+	var first *downloadSegment
+	if d.state != nil {
+		pending := d.state.pending()
+		if len(pending) == 0 {
+			return nil
+		}
+		first = pending[0]
+		b.req.Header.Set("Range", first.rangeHeader())
+		b.req.Header.Set("If-Range", d.state.Validator)
+	} else if segments > 1 && b.req.Method == "GET" {
+		b.req.Header.Set("Range", "bytes=0-")
+	}
+	resp, err := b.DoRequest()
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok {
+			return errors.New("http: invalid Content-Range " + resp.Header.Get("Content-Range"))
+		}
+		if d.state == nil {
+			d.state = newDownloadState(resp, total, segments)
+			first = d.state.Segments[0]
+		}
+		if start != first.next() {
+			return fmt.Errorf("http: unexpected range start %d, want %d", start, first.next())
+		}
+	case http.StatusOK:
+		d.state = newDownloadState(resp, resp.ContentLength, 1)
+		first = d.state.Segments[0]
+		if err := d.file.Truncate(0); err != nil {
+			return err
+		}
+	default:
+		return newHTTPError(b.req, resp, readErrorBody(resp))
+	}
+	d.written = d.state.done()
+	d.saved = d.written
+	d.state.save(d.metaPath)
+	d.advance(nil, 0)
+
+	ctx, cancel := context.WithCancel(b.req.Context())
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		errMu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+			_ = resp.Body.Close()
+		}
+		errMu.Unlock()
+	}
+	send := b.handler(b.httpClient())
+	for _, seg := range d.state.pending() {
+		if seg == first {
+			continue
+		}
+		wg.Add(1)
+		go func(seg *downloadSegment) {
+			defer wg.Done()
+			if err := d.fetch(ctx, send, seg); err != nil {
+				fail(err)
+			}
+		}(seg)
+	}
+	if err := d.copy(resp.Body, first); err != nil {
+		fail(err)
+	}
+	// 首个分段写完后立即关闭响应体，归还限流器的并发名额，其他分段才能继续发送。
+	_ = resp.Body.Close()
+	wg.Wait()
+	if firstErr != nil && b.req.Context().Err() != nil {
+		return b.req.Context().Err()
+	}
+	return firstErr
+}
+
+// fetch 使用新的 Range 请求下载一个分段，服务端文件变化时返回错误。
+func (d *downloader) fetch(ctx context.Context, send Handler, seg *downloadSegment) error {
+	req := d.req.req.Clone(ctx)
+	req.Header.Set("Range", seg.rangeHeader())
+	if d.state.Validator != "" {
+		req.Header.Set("If-Range", d.state.Validator)
+	}
+	resp, err := send(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		return errors.New("http: the remote file changed during download")
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return newHTTPError(req, resp, readErrorBody(resp))
+	}
+	if start, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != seg.next() {
+		return fmt.Errorf("http: unexpected range %q, want %s", resp.Header.Get("Content-Range"), seg.rangeHeader())
+	}
+	return d.copy(resp.Body, seg)
+}
+
+// copy 把 body 写入分段对应的文件位置，总大小未知时读到 EOF 后确定分段结束位置。
+func (d *downloader) copy(body io.Reader, seg *downloadSegment) error {
+	if seg.End >= 0 {
+		body = io.LimitReader(body, seg.End-seg.next()+1)
+	}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, err := d.file.WriteAt(buf[:n], seg.next()); err != nil {
+				return err
+			}
+			d.advance(seg, int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if seg.End < 0 {
+		d.mu.Lock()
+		seg.End = seg.next() - 1
+		d.state.Size = seg.next()
+		d.mu.Unlock()
+	}
+	if !seg.complete() {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// advance 记录分段新写入的 n 个字节并回调进度，每写入 metaSaveInterval 字节保存一次断点续传信息。
+// 分段进度在 d.mu 保护下修改，保存时不会与其他分段的写入冲突。
+func (d *downloader) advance(seg *downloadSegment, n int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if seg != nil {
+		seg.Done += n
+	}
+	d.written += n
+	if d.progress != nil {
+		d.progress(d.written, d.state.Size)
+	}
+	if d.written-d.saved >= metaSaveInterval {
+		d.state.save(d.metaPath)
+		d.saved = d.written
+	}
+}
+
+// newDownloadState 按响应的校验信息和总大小规划分段，总大小未知、过小或没有校验信息时只使用一个分段，
+// 没有校验信息的分段请求无法通过 If-Range 发现服务端文件已变化。
+func newDownloadState(resp *http.Response, total int64, segments int) *downloadState {
+	state := &downloadState{Validator: rangeValidator(resp), Size: total}
+	if state.Validator == "" {
+		segments = 1
+	}
+	if total < 0 {
+		state.Size = -1
+		state.Segments = []*downloadSegment{{Start: 0, End: -1}}
+		return state
+	}
+	if segments < 1 {
+		segments = 1
+	}
+	if limit := int(total / minSegmentSize); segments > limit {
+		segments = max(limit, 1)
+	}
+	size := total / int64(segments)
+	for i := 0; i < segments; i++ {
+		seg := &downloadSegment{Start: int64(i) * size, End: int64(i+1)*size - 1}
+		if i == segments-1 {
+			seg.End = total - 1
+		}
+		state.Segments = append(state.Segments, seg)
+	}
+	return state
+}
+
+// rangeValidator 返回可用于 If-Range 的强 ETag 或 Last-Modified，弱 ETag 不能用于 If-Range。
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// parseContentRange 解析 "bytes start-end/total"，total 为 * 时返回 -1。
+func parseContentRange(value string) (start, total int64, ok bool) {
+	value, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, size, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if size == "*" {
+		return start, -1, true
+	}
+	total, err = strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+func verifyDownload(f *os.File, size int64, opts DownloadOptions) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if size >= 0 && info.Size() != size {
+		return fmt.Errorf("http: incomplete download, got %d bytes, want %d", info.Size(), size)
+	}
+	if opts.Size > 0 && info.Size() != opts.Size {
+		return fmt.Errorf("http: download size mismatch, got %d, want %d", info.Size(), opts.Size)
+	}
+	if opts.MD5 == "" && opts.SHA256 == "" {
+		return nil
+	}
+	var writers []io.Writer
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if opts.MD5 != "" {
+		writers = append(writers, md5Hash)
+	}
+	if opts.SHA256 != "" {
+		writers = append(writers, sha256Hash)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), io.NewSectionReader(f, 0, info.Size())); err != nil {
+		return err
+	}
+	if opts.MD5 != "" && !strings.EqualFold(hex.EncodeToString(md5Hash.Sum(nil)), opts.MD5) {
+		return errors.New("http: download md5 checksum mismatch")
+	}
+	if opts.SHA256 != "" && !strings.EqualFold(hex.EncodeToString(sha256Hash.Sum(nil)), opts.SHA256) {
+		return errors.New("http: download sha256 checksum mismatch")
+	}
+	return nil
+}
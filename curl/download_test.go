@@ -0,0 +1,199 @@
+package curl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value string
+		start int64
+		total int64
+		ok    bool
+	}{
+		{"bytes 0-99/1000", 0, 1000, true},
+		{"bytes 500-999/1000", 500, 1000, true},
+		{"bytes 10-/*", 10, -1, true},
+		{"bytes 10-20/*", 10, -1, true},
+		{"", 0, 0, false},
+		{"bytes */1000", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes 0-9", 0, 0, false},
+		{"bytes x-9/10", 0, 0, false},
+		{"bytes 0-9/many", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.value)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v; want %d, %d, %v",
+				tt.value, start, total, ok, tt.start, tt.total, tt.ok)
+		}
+	}
+}
+
+func TestNewDownloadStatePlansSegments(t *testing.T) {
+	withETag := &http.Response{Header: http.Header{"Etag": []string{`"v1"`}}}
+	weakETag := &http.Response{Header: http.Header{"Etag": []string{`W/"v1"`}}}
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		total    int64
+		segments int
+		want     [][2]int64
+	}{
+		{"unknown size", withETag, -1, 4, [][2]int64{{0, -1}}},
+		{"no validator", weakETag, 4 * minSegmentSize, 4, [][2]int64{{0, 4*minSegmentSize - 1}}},
+		{"too small", withETag, minSegmentSize - 1, 4, [][2]int64{{0, minSegmentSize - 2}}},
+		{"limited by size", withETag, 2*minSegmentSize + 1, 4, [][2]int64{
+			{0, minSegmentSize - 1}, {minSegmentSize, 2 * minSegmentSize},
+		}},
+		{"even split", withETag, 3 * minSegmentSize, 3, [][2]int64{
+			{0, minSegmentSize - 1}, {minSegmentSize, 2*minSegmentSize - 1}, {2 * minSegmentSize, 3*minSegmentSize - 1},
+		}},
+		{"remainder in last", withETag, 2*minSegmentSize + 3, 2, [][2]int64{
+			{0, minSegmentSize}, {minSegmentSize + 1, 2*minSegmentSize + 2},
+		}},
+	}
+	for _, tt := range tests {
+		state := newDownloadState(tt.resp, tt.total, tt.segments)
+		var got [][2]int64
+		for _, seg := range state.Segments {
+			got = append(got, [2]int64{seg.Start, seg.End})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: segments = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newDownloadServer 返回支持 Range 和 If-Range 的测试服务，并记录每次请求的 Range 请求头。
+func newDownloadServer(t *testing.T, content []byte, etag string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+func testContent(size int) ([]byte, string) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+	sum := sha256.Sum256(content)
+	return content, hex.EncodeToString(sum[:])
+}
+
+func TestDownloadSegmented(t *testing.T) {
+	content, sum := testContent(3*minSegmentSize + 5)
+	srv, ranges := newDownloadServer(t, content, `"v1"`)
+	filename := filepath.Join(t.TempDir(), "file.bin")
+
+	var last, total int64
+	err := Get(srv.URL).Download(filename, DownloadOptions{
+		Segments: 3,
+		SHA256:   sum,
+		Progress: func(n, size int64) {
+			if n < last {
+				t.Errorf("progress went back from %d to %d", last, n)
+			}
+			last, total = n, size
+		},
+	})
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+	if got, _ := os.ReadFile(filename); !bytes.Equal(got, content) {
+		t.Error("downloaded content differs from the source")
+	}
+	if last != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("final progress = %d/%d, want %d/%d", last, total, len(content), len(content))
+	}
+	if got := len(ranges()); got != 3 {
+		t.Errorf("sent %d requests, want 3: %q", got, ranges())
+	}
+	for _, name := range []string{filename + ".download", filename + ".download.meta"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", filepath.Base(name))
+		}
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content, sum := testContent(1000)
+	tests := []struct {
+		name      string
+		validator string
+		wantRange string
+	}{
+		{"same file", `"v1"`, "bytes=400-999"},
+		{"changed file", `"v0"`, "bytes=400-999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, ranges := newDownloadServer(t, content, `"v1"`)
+			filename := filepath.Join(t.TempDir(), "file.bin")
+			partial := content[:400]
+			if tt.validator != `"v1"` {
+				partial = bytes.Repeat([]byte("x"), 400)
+			}
+			if err := os.WriteFile(filename+".download", partial, 0644); err != nil {
+				t.Fatal(err)
+			}
+			meta, _ := json.Marshal(downloadState{
+				Validator: tt.validator,
+				Size:      1000,
+				Segments:  []*downloadSegment{{Start: 0, End: 999}},
+			})
+			if err := os.WriteFile(filename+".download.meta", meta, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Get(srv.URL).Download(filename, DownloadOptions{SHA256: sum}); err != nil {
+				t.Fatalf("Download() error: %v", err)
+			}
+			if got, _ := os.ReadFile(filename); !bytes.Equal(got, content) {
+				t.Error("resumed content differs from the source")
+			}
+			if got := ranges(); len(got) != 1 || got[0] != tt.wantRange {
+				t.Errorf("Range headers = %q, want [%q]", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	content, _ := testContent(100)
+	srv, _ := newDownloadServer(t, content, `"v1"`)
+	filename := filepath.Join(t.TempDir(), "file.bin")
+	err := Get(srv.URL).Download(filename, DownloadOptions{SHA256: strings.Repeat("0", 64)})
+	if err == nil || !strings.Contains(err.Error(), "sha256 checksum mismatch") {
+		t.Fatalf("Download() error = %v, want a checksum mismatch", err)
+	}
+	for _, name := range []string{filename, filename + ".download", filename + ".download.meta"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s exists after a failed checksum", filepath.Base(name))
+		}
+	}
+}
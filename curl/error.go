@@ -0,0 +1,103 @@
+package curl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxErrorBodySize 为 HTTPError 保留的响应体最大字节数。
+const maxErrorBodySize = 4096
+
+// HTTPError 表示服务端返回了非 2xx 状态码，Body 最多保留前 4KB。
+//
+//	var httpErr *curl.HTTPError
+//	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+//	    ...
+//	}
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > 256 {
+		body = body[:256]
+	}
+	if len(body) == 0 {
+		return fmt.Sprintf("http: %s %s: %s", e.Method, e.URL, e.Status)
+	}
+	return fmt.Sprintf("http: %s %s: %s: %s", e.Method, e.URL, e.Status, body)
+}
+
+// ExpectSuccess 开启后 Bytes、String、ToJSON、ToXML、ToYAML、ToFile 在非 2xx 响应时返回 *HTTPError，
+// 不再解析或写入响应体。
+func (b *Request) ExpectSuccess(expect bool) *Request {
+	b.setting.ExpectSuccess = expect
+	return b
+}
+
+func (c *Client) SetExpectSuccess(expect bool) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.ExpectSuccess = expect
+	return c
+}
+
+// ToJSONWithError 在 2xx 响应时把响应体解析到 v，否则把响应体解析到 errV 并返回 *HTTPError，
+// 响应体不是合法 JSON 时 errV 保持不变，仍返回 *HTTPError。
+//
+//	var out User
+//	var apiErr struct{ Code int; Message string }
+//	err := client.Get("/users/1").ToJSONWithError(&out, &apiErr)
+func (b *Request) ToJSONWithError(v, errV interface{}) error {
+	data, err := b.readBody()
+	if err != nil {
+		return err
+	}
+	if httpErr := b.statusError(); httpErr != nil {
+		if errV != nil {
+			_ = json.Unmarshal(data, errV)
+		}
+		return httpErr
+	}
+	return json.Unmarshal(data, v)
+}
+
+// statusError 在最近一次响应为非 2xx 时返回 *HTTPError。
+func (b *Request) statusError() *HTTPError {
+	if b.resp == nil || (b.resp.StatusCode >= 200 && b.resp.StatusCode <= 299) {
+		return nil
+	}
+	return newHTTPError(b.req, b.resp, b.body)
+}
+
+func newHTTPError(req *http.Request, resp *http.Response, body []byte) *HTTPError {
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+	return &HTTPError{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       append([]byte(nil), body...),
+	}
+}
+
+// readErrorBody 读取未被读取的响应体前 4KB 并关闭响应体，用于构造 HTTPError。
+func readErrorBody(resp *http.Response) []byte {
+	if resp.Body == nil {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	_ = resp.Body.Close()
+	return body
+}
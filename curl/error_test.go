@@ -0,0 +1,118 @@
+package curl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTTPErrorMessage(t *testing.T) {
+	tests := []struct {
+		body []byte
+		want string
+	}{
+		{nil, "http: GET /a: 404 Not Found"},
+		{[]byte("missing"), "http: GET /a: 404 Not Found: missing"},
+		{[]byte(strings.Repeat("x", 300)), "http: GET /a: 404 Not Found: " + strings.Repeat("x", 256)},
+	}
+	for _, tt := range tests {
+		err := &HTTPError{Method: "GET", URL: "/a", StatusCode: 404, Status: "404 Not Found", Body: tt.body}
+		if got := err.Error(); got != tt.want {
+			t.Errorf("Error() with %d body bytes = %q, want %q", len(tt.body), got, tt.want)
+		}
+	}
+}
+
+func TestExpectSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"name":"tom"}`))
+		case "/big":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(strings.Repeat("e", maxErrorBodySize+100)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":40401,"message":"user not found"}`))
+		}
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL).SetExpectSuccess(true)
+
+	var user struct{ Name string }
+	if err := client.Get("/ok").ToJSON(&user); err != nil || user.Name != "tom" {
+		t.Fatalf("ToJSON(/ok) = %+v, %v", user, err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		size   int
+	}{
+		{"/missing", http.StatusNotFound, 41},
+		{"/big", http.StatusBadGateway, maxErrorBodySize},
+	}
+	for _, tt := range tests {
+		_, err := client.Get(tt.path).String()
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("String(%s) error = %v, want *HTTPError", tt.path, err)
+		}
+		if httpErr.StatusCode != tt.status || httpErr.Method != "GET" || len(httpErr.Body) != tt.size {
+			t.Errorf("String(%s) error = %d %s with %d body bytes, want %d and %d bytes",
+				tt.path, httpErr.StatusCode, httpErr.Method, len(httpErr.Body), tt.status, tt.size)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "out")
+	if err := client.Get("/missing").ToFile(file); err == nil {
+		t.Error("ToFile(/missing) returned no error")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("ToFile(/missing) created %s, stat error = %v", file, err)
+	}
+
+	if body, err := NewRequest(srv.URL+"/missing", "GET").String(); err != nil || !strings.Contains(body, "40401") {
+		t.Errorf("String() without ExpectSuccess = %q, %v; want the body", body, err)
+	}
+}
+
+func TestToJSONWithError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/html" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("<html>oops</html>"))
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"code":409,"message":"duplicate"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		status  int
+		message string
+	}{
+		{"/conflict", http.StatusConflict, "duplicate"},
+		{"/html", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		var out, apiErr struct {
+			Code    int
+			Message string
+		}
+		err := NewRequest(srv.URL+tt.path, "GET").ToJSONWithError(&out, &apiErr)
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.status {
+			t.Fatalf("ToJSONWithError(%s) error = %v, want status %d", tt.path, err, tt.status)
+		}
+		if apiErr.Message != tt.message || out.Code != 0 {
+			t.Errorf("ToJSONWithError(%s) decoded out=%+v errV=%+v, want message %q", tt.path, out, apiErr, tt.message)
+		}
+	}
+}
@@ -9,14 +9,14 @@ import (
 	"encoding/xml"
 	"io"
 	"log"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httputil"
 	"net/url"
 	"os"
-	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -62,13 +62,11 @@ func NewRequest(rawurl, method string) *Request {
 		ProtoMinor: 1,
 	}
 	return &Request{
-		url:      rawurl,
-		req:      &req,
-		params:   map[string][]string{},
-		files:    map[string]string{},
-		filename: map[string]string{},
-		setting:  defaultSetting,
-		resp:     &resp,
+		url:     rawurl,
+		req:     &req,
+		params:  map[string][]string{},
+		setting: defaultSetting,
+		resp:    &resp,
 	}
 }
 
@@ -105,18 +103,31 @@ type Setting struct {
 	Gzip             bool
 	DumpBody         bool
 	Retries          int
+	RetryPolicy      *RetryPolicy
+	ExpectSuccess    bool
+	RateLimiter      *RateLimiter
+	CircuitBreaker   *CircuitBreaker
 }
 
 type Request struct {
 	url      string
 	req      *http.Request
 	params   map[string][]string
-	files    map[string]string
-	filename map[string]string
 	setting  Setting
+	client   *Client
+	attempts int
+	stream   bool
 	resp     *http.Response
 	body     []byte
 	dump     []byte
+
+	parts          []*formPart
+	uploadProgress func(sent, total int64)
+	middlewares    []Middleware
+	reconnects     int
+	reconnectDelay time.Duration
+	// ownTransport 表示客户端请求覆盖了连接相关设置，不能复用客户端的连接池。
+	ownTransport bool
 }
 
 func (b *Request) GetRequest() *http.Request {
@@ -129,6 +140,9 @@ func (b *Request) WithContext(ctx context.Context) *Request {
 }
 
 func (b *Request) Setting(setting Setting) *Request {
+	if !sameTransportSetting(b.setting, setting) {
+		b.ownTransport = true
+	}
 	b.setting = setting
 	return b
 }
@@ -170,11 +184,13 @@ func (b *Request) DumpRequest() []byte {
 func (b *Request) SetTimeout(connectTimeout, readWriteTimeout time.Duration) *Request {
 	b.setting.ConnectTimeout = connectTimeout
 	b.setting.ReadWriteTimeout = readWriteTimeout
+	b.ownTransport = true
 	return b
 }
 
 func (b *Request) SetTLSClientConfig(config *tls.Config) *Request {
 	b.setting.TLSClientConfig = config
+	b.ownTransport = true
 	return b
 }
 
@@ -213,6 +229,7 @@ func (b *Request) SetTransport(transport http.RoundTripper) *Request {
 
 func (b *Request) SetProxy(proxy func(*http.Request) (*url.URL, error)) *Request {
 	b.setting.Proxy = proxy
+	b.ownTransport = true
 	return b
 }
 
@@ -231,12 +248,14 @@ func (b *Request) Param(key, value string) *Request {
 }
 
 func (b *Request) PostFile(formname, filename string, argv ...string) *Request {
-	b.files[formname] = filename
-	if len(argv) > 0 && len(argv[0]) > 0 {
-		b.filename[formname] = argv[0]
-	} else {
-		b.filename[formname] = filepath.Base(filename)
+	part := filePart(formname, filename, argv...)
+	for i, p := range b.parts {
+		if p.path != "" && p.name == formname {
+			b.parts[i] = part
+			return b
+		}
 	}
+	b.parts = append(b.parts, part)
 	return b
 }
 
@@ -320,35 +339,8 @@ func (b *Request) buildURL(paramBody string) {
 	if (b.req.Method == "POST" || b.req.Method == "PUT" ||
 		b.req.Method == "PATCH" || b.req.Method == "DELETE") &&
 		b.req.Body == nil {
-		if len(b.files) > 0 {
-			pr, pw := io.Pipe()
-			bodyWriter := multipart.NewWriter(pw)
-			go func() {
-				for formname, filename := range b.files {
-					fileWriter, err := bodyWriter.CreateFormFile(formname, b.filename[formname])
-					if err != nil {
-						log.Println("http:", err)
-					}
-					fh, err := os.Open(filename)
-					if err != nil {
-						log.Println("http:", err)
-					}
-					_, err = io.Copy(fileWriter, fh)
-					_ = fh.Close()
-					if err != nil {
-						log.Println("http:", err)
-					}
-				}
-				for k, v := range b.params {
-					for _, vv := range v {
-						_ = bodyWriter.WriteField(k, vv)
-					}
-				}
-				_ = bodyWriter.Close()
-				_ = pw.Close()
-			}()
-			b.Header("Content-Type", bodyWriter.FormDataContentType())
-			b.req.Body = io.NopCloser(pr)
+		if b.hasParts() {
+			b.buildMultipart()
 			return
 		}
 		if len(paramBody) > 0 {
@@ -358,6 +350,16 @@ func (b *Request) buildURL(paramBody string) {
 	}
 }
 
+// paramKeys 返回排序后的参数名，保证查询串和表单请求体的参数顺序稳定。
+func (b *Request) paramKeys() []string {
+	keys := make([]string, 0, len(b.params))
+	for k := range b.params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (b *Request) getResponse() (*http.Response, error) {
 	if b.resp.StatusCode != 0 {
 		return b.resp, nil
@@ -374,8 +376,8 @@ func (b *Request) DoRequest() (resp *http.Response, err error) {
 	var paramBody string
 	if len(b.params) > 0 {
 		var buf bytes.Buffer
-		for k, v := range b.params {
-			for _, vv := range v {
+		for _, k := range b.paramKeys() {
+			for _, vv := range b.params[k] {
 				buf.WriteString(url.QueryEscape(k))
 				buf.WriteByte('=')
 				buf.WriteString(url.QueryEscape(vv))
@@ -391,19 +393,27 @@ func (b *Request) DoRequest() (resp *http.Response, err error) {
 		return nil, err
 	}
 	b.req.URL = urlParsed
+	client := b.httpClient()
+	if b.setting.UserAgent != "" && b.req.Header.Get("User-Agent") == "" {
+		b.req.Header.Set("User-Agent", b.setting.UserAgent)
+	}
+	if b.setting.ShowDebug {
+		dump, err := httputil.DumpRequest(b.req, b.setting.DumpBody)
+		if err != nil {
+			log.Println(err.Error())
+		}
+		b.dump = dump
+	}
+	return b.doWithRetry(client)
+}
+
+func (b *Request) httpClient() *http.Client {
 	trans := b.setting.Transport
 	if trans == nil {
-		trans = &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   b.setting.ConnectTimeout,
-				KeepAlive: b.setting.ConnectTimeout + b.setting.ReadWriteTimeout,
-			}).DialContext,
-			TLSClientConfig:       b.setting.TLSClientConfig,
-			Proxy:                 b.setting.Proxy,
-			MaxIdleConnsPerHost:   100,
-			DisableKeepAlives:     true,
-			ResponseHeaderTimeout: b.setting.ReadWriteTimeout,
-			TLSHandshakeTimeout:   b.setting.ConnectTimeout,
+		if b.client != nil && !b.ownTransport {
+			trans = b.client.getTransport()
+		} else {
+			trans = newTransport(b.setting, false)
 		}
 	} else {
 		if t, ok := trans.(*http.Transport); ok {
@@ -416,7 +426,9 @@ func (b *Request) DoRequest() (resp *http.Response, err error) {
 		}
 	}
 	var jar http.CookieJar
-	if b.setting.EnableCookie {
+	if b.client != nil {
+		jar = b.client.getCookieJar(b.setting.EnableCookie)
+	} else if b.setting.EnableCookie {
 		if defaultCookieJar == nil {
 			createDefaultCookie()
 		}
@@ -427,26 +439,36 @@ func (b *Request) DoRequest() (resp *http.Response, err error) {
 		Jar:       jar,
 		Timeout:   b.setting.ReadWriteTimeout,
 	}
-	if b.setting.UserAgent != "" && b.req.Header.Get("User-Agent") == "" {
-		b.req.Header.Set("User-Agent", b.setting.UserAgent)
+	if b.stream {
+		client.Timeout = 0
 	}
 	if b.setting.CheckRedirect != nil {
 		client.CheckRedirect = b.setting.CheckRedirect
 	}
-	if b.setting.ShowDebug {
-		dump, err := httputil.DumpRequest(b.req, b.setting.DumpBody)
-		if err != nil {
-			log.Println(err.Error())
-		}
-		b.dump = dump
-	}
-	for i := 0; b.setting.Retries == -1 || i <= b.setting.Retries; i++ {
-		resp, err = client.Do(b.req)
-		if err == nil {
-			break
-		}
+	return client
+}
+
+// sameTransportSetting 判断两组设置创建的 Transport 是否相同，Proxy 按函数地址比较。
+func sameTransportSetting(a, b Setting) bool {
+	return a.ConnectTimeout == b.ConnectTimeout &&
+		a.ReadWriteTimeout == b.ReadWriteTimeout &&
+		a.TLSClientConfig == b.TLSClientConfig &&
+		reflect.ValueOf(a.Proxy).Pointer() == reflect.ValueOf(b.Proxy).Pointer()
+}
+
+func newTransport(setting Setting, keepAlive bool) *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   setting.ConnectTimeout,
+			KeepAlive: setting.ConnectTimeout + setting.ReadWriteTimeout,
+		}).DialContext,
+		TLSClientConfig:       setting.TLSClientConfig,
+		Proxy:                 setting.Proxy,
+		MaxIdleConnsPerHost:   100,
+		DisableKeepAlives:     !keepAlive,
+		ResponseHeaderTimeout: setting.ReadWriteTimeout,
+		TLSHandshakeTimeout:   setting.ConnectTimeout,
 	}
-	return resp, err
 }
 
 func (b *Request) String() (string, error) {
@@ -458,6 +480,19 @@ func (b *Request) String() (string, error) {
 }
 
 func (b *Request) Bytes() ([]byte, error) {
+	data, err := b.readBody()
+	if err != nil {
+		return nil, err
+	}
+	if b.setting.ExpectSuccess {
+		if httpErr := b.statusError(); httpErr != nil {
+			return nil, httpErr
+		}
+	}
+	return data, nil
+}
+
+func (b *Request) readBody() ([]byte, error) {
 	if b.body != nil {
 		return b.body, nil
 	}
@@ -484,6 +519,19 @@ func (b *Request) Bytes() ([]byte, error) {
 }
 
 func (b *Request) ToFile(filename string) error {
+	// 先检查状态码再创建文件，非 2xx 响应不会截断已存在的文件。
+	resp, err := b.getResponse()
+	if err != nil {
+		return err
+	}
+	if resp.Body != nil {
+		defer func(Body io.ReadCloser) {
+			_ = Body.Close()
+		}(resp.Body)
+		if b.setting.ExpectSuccess && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+			return newHTTPError(b.req, resp, readErrorBody(resp))
+		}
+	}
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -491,16 +539,9 @@ func (b *Request) ToFile(filename string) error {
 	defer func(f *os.File) {
 		_ = f.Close()
 	}(f)
-	resp, err := b.getResponse()
-	if err != nil {
-		return err
-	}
 	if resp.Body == nil {
 		return nil
 	}
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
 	_, err = io.Copy(f, resp.Body)
 	return err
 }
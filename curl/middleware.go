@@ -0,0 +1,109 @@
+package curl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Handler 发送一次 HTTP 请求并返回响应。
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware 包装 Handler，可以在请求发送前修改 *http.Request，在返回后检查响应或错误。
+// 中间件按每次实际发送执行，请求重试时会再次经过整个调用链。
+//
+//	sign := func(next curl.Handler) curl.Handler {
+//	    return func(req *http.Request) (*http.Response, error) {
+//	        req.Header.Set("X-Sign", sign(req))
+//	        return next(req)
+//	    }
+//	}
+//	client := curl.NewClient(baseURL).Use(curl.RequestIDMiddleware("", nil), sign)
+type Middleware func(next Handler) Handler
+
+// Use 为客户端追加中间件，只对之后创建的请求生效，客户端中间件先于请求中间件执行。
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middlewares = append(c.middlewares, middlewares...)
+	return c
+}
+
+// Use 为当前请求追加中间件，先追加的中间件在调用链外层。
+func (b *Request) Use(middlewares ...Middleware) *Request {
+	b.middlewares = append(b.middlewares, middlewares...)
+	return b
+}
+
+func (b *Request) handler(client *http.Client) Handler {
+	h := Handler(client.Do)
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		h = b.middlewares[i](h)
+	}
+	// 熔断器在限流器内层，客户端自身限流产生的错误不会被计为下游故障。
+	if b.setting.CircuitBreaker != nil {
+		h = b.setting.CircuitBreaker.wrap(h)
+	}
+	if b.setting.RateLimiter != nil {
+		h = b.setting.RateLimiter.wrap(h)
+	}
+	return h
+}
+
+// LoggingMiddleware 记录请求方法、地址、状态码、耗时和错误，logf 为空时使用 log.Printf。
+func LoggingMiddleware(logf func(format string, v ...interface{})) Middleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				logf("http: %s %s error=%v latency=%s", req.Method, req.URL, err, time.Since(start))
+			} else {
+				logf("http: %s %s status=%d latency=%s", req.Method, req.URL, resp.StatusCode, time.Since(start))
+			}
+			return resp, err
+		}
+	}
+}
+
+// RequestIDMiddleware 在请求没有 header 请求头时写入请求 ID，header 为空时使用 X-Request-Id，
+// generate 为空时生成 32 位随机十六进制字符串。重试时沿用首次生成的请求 ID。
+func RequestIDMiddleware(header string, generate func() string) Middleware {
+	if header == "" {
+		header = "X-Request-Id"
+	}
+	if generate == nil {
+		generate = newRequestID
+	}
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				req.Header.Set(header, generate())
+			}
+			return next(req)
+		}
+	}
+}
+
+// LatencyMiddleware 在每次发送结束后回调本次请求的耗时，可用于上报监控指标。
+func LatencyMiddleware(observe func(req *http.Request, resp *http.Response, err error, latency time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			observe(req, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,115 @@
+package curl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// traceMiddleware 在请求前后记录 name，用于检查中间件调用顺序。
+func traceMiddleware(trace *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			*trace = append(*trace, name+">")
+			resp, err := next(req)
+			*trace = append(*trace, "<"+name)
+			return resp, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Request-Id")))
+	}))
+	defer srv.Close()
+
+	var trace []string
+	client := NewClient(srv.URL).Use(traceMiddleware(&trace, "client"))
+	body, err := client.Get("/").Use(traceMiddleware(&trace, "a"), traceMiddleware(&trace, "b")).String()
+	if err != nil {
+		t.Fatalf("String() error: %v", err)
+	}
+	if want := []string{"client>", "a>", "b>", "<b", "<a", "<client"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("middleware calls = %v, want %v", trace, want)
+	}
+	if body != "" {
+		t.Errorf("request id = %q, want none without RequestIDMiddleware", body)
+	}
+
+	trace = nil
+	client.Use(traceMiddleware(&trace, "late"))
+	if _, err := client.Get("/").String(); err != nil {
+		t.Fatalf("String() error: %v", err)
+	}
+	if want := []string{"client>", "late>", "<late", "<client"}; !reflect.DeepEqual(trace, want) {
+		t.Errorf("middleware calls after Use = %v, want %v", trace, want)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Request-Id") + "|" + r.Header.Get("X-Trace")))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		mw     Middleware
+		header string
+		check  func(string) bool
+	}{
+		{"generated", RequestIDMiddleware("", nil), "", func(s string) bool {
+			id, _, _ := strings.Cut(s, "|")
+			return len(id) == 32
+		}},
+		{"custom header", RequestIDMiddleware("X-Trace", func() string { return "t-1" }), "", func(s string) bool {
+			return s == "|t-1"
+		}},
+		{"keeps existing", RequestIDMiddleware("", nil), "given", func(s string) bool {
+			return s == "given|"
+		}},
+	}
+	for _, tt := range tests {
+		req := NewRequest(srv.URL, "GET").Use(tt.mw)
+		if tt.header != "" {
+			req.Header("X-Request-Id", tt.header)
+		}
+		body, err := req.String()
+		if err != nil {
+			t.Fatalf("%s: String() error: %v", tt.name, err)
+		}
+		if !tt.check(body) {
+			t.Errorf("%s: server saw %q", tt.name, body)
+		}
+	}
+}
+
+func TestLoggingAndLatencyMiddleware(t *testing.T) {
+	var logs []string
+	logf := func(format string, v ...interface{}) {
+		logs = append(logs, format)
+	}
+	var observed []int
+	observe := func(req *http.Request, resp *http.Response, err error, latency time.Duration) {
+		if err != nil || latency < 0 {
+			observed = append(observed, -1)
+			return
+		}
+		observed = append(observed, resp.StatusCode)
+	}
+	h := LoggingMiddleware(logf)(LatencyMiddleware(observe)(statusHandler(http.StatusTeapot)))
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	if _, err := h(req); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if len(logs) != 1 || !strings.Contains(logs[0], "status=") {
+		t.Errorf("logs = %v, want one status line", logs)
+	}
+	if !reflect.DeepEqual(observed, []int{http.StatusTeapot}) {
+		t.Errorf("observed = %v, want [418]", observed)
+	}
+}
@@ -0,0 +1,222 @@
+package curl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formPart 表示 multipart 请求中的一个文件分段，数据来源为文件路径、字节切片或 io.Reader 之一。
+type formPart struct {
+	name        string
+	filename    string
+	contentType string
+	path        string
+	data        []byte
+	reader      io.Reader
+	used        bool
+}
+
+// PostReader 添加一个数据来自 r 的文件分段，contentType 为空时使用 application/octet-stream。
+// r 只能读取一次，包含 PostReader 分段的请求不会重试。
+func (b *Request) PostReader(formname, filename string, r io.Reader, contentType string) *Request {
+	b.parts = append(b.parts, &formPart{name: formname, filename: filename, contentType: contentType, reader: r})
+	return b
+}
+
+// PostBytes 添加一个数据为 data 的文件分段，contentType 为空时使用 application/octet-stream。
+func (b *Request) PostBytes(formname, filename string, data []byte, contentType string) *Request {
+	b.parts = append(b.parts, &formPart{name: formname, filename: filename, contentType: contentType, data: data})
+	return b
+}
+
+// UploadProgress 设置 multipart 上传进度回调，参数为已发送的请求体字节数和总字节数，总字节数未知时为 -1。
+func (b *Request) UploadProgress(fn func(sent, total int64)) *Request {
+	b.uploadProgress = fn
+	return b
+}
+
+// hasParts 表示请求需要以 multipart/form-data 发送。
+func (b *Request) hasParts() bool {
+	return len(b.parts) > 0
+}
+
+// buildMultipart 按添加顺序写入文件分段，再按名称顺序写入普通参数。
+// 打开或读取分段失败时通过 CloseWithError 中止请求体，调用方会收到该错误。
+func (b *Request) buildMultipart() {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	total := b.multipartSize(boundary)
+	b.req.Body = b.multipartBody(boundary, total)
+	if total >= 0 {
+		b.req.ContentLength = total
+	}
+	if b.rewindable() {
+		b.req.GetBody = func() (io.ReadCloser, error) {
+			return b.multipartBody(boundary, total), nil
+		}
+	}
+	b.Header("Content-Type", "multipart/form-data; boundary="+boundary)
+}
+
+func (b *Request) multipartBody(boundary string, total int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		bodyWriter := multipart.NewWriter(pw)
+		_ = bodyWriter.SetBoundary(boundary)
+		err := b.writeMultipart(bodyWriter)
+		if err == nil {
+			err = bodyWriter.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	if b.uploadProgress == nil {
+		return pr
+	}
+	return &progressReader{ReadCloser: pr, total: total, fn: b.uploadProgress}
+}
+
+func (b *Request) writeMultipart(bodyWriter *multipart.Writer) error {
+	for _, part := range b.parts {
+		reader, err := part.open()
+		if err != nil {
+			return err
+		}
+		partWriter, err := bodyWriter.CreatePart(part.header())
+		if err != nil {
+			_ = reader.Close()
+			return err
+		}
+		_, err = io.Copy(partWriter, reader)
+		_ = reader.Close()
+		if err != nil {
+			return fmt.Errorf("http: write form file %s: %w", part.name, err)
+		}
+	}
+	for _, k := range b.paramKeys() {
+		for _, v := range b.params[k] {
+			if err := bodyWriter.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// multipartSize 计算请求体长度，存在长度未知的 io.Reader 分段或文件无法读取时返回 -1。
+func (b *Request) multipartSize(boundary string) int64 {
+	counter := &countWriter{}
+	bodyWriter := multipart.NewWriter(counter)
+	_ = bodyWriter.SetBoundary(boundary)
+	for _, part := range b.parts {
+		size := part.size()
+		if size < 0 {
+			return -1
+		}
+		if _, err := bodyWriter.CreatePart(part.header()); err != nil {
+			return -1
+		}
+		counter.n += size
+	}
+	for _, k := range b.paramKeys() {
+		for _, v := range b.params[k] {
+			_ = bodyWriter.WriteField(k, v)
+		}
+	}
+	_ = bodyWriter.Close()
+	return counter.n
+}
+
+func (b *Request) rewindable() bool {
+	for _, part := range b.parts {
+		if part.reader != nil {
+			return false
+		}
+	}
+	return true
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (p *formPart) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(p.name), quoteEscaper.Replace(p.filename)))
+	contentType := p.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+func (p *formPart) open() (io.ReadCloser, error) {
+	switch {
+	case p.reader != nil:
+		if p.used {
+			return nil, errors.New("http: form file " + p.name + " reader has already been read")
+		}
+		p.used = true
+		return io.NopCloser(p.reader), nil
+	case p.data != nil:
+		return io.NopCloser(bytes.NewReader(p.data)), nil
+	case p.path != "":
+		return os.Open(p.path)
+	}
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+func (p *formPart) size() int64 {
+	switch {
+	case p.reader != nil:
+		if r, ok := p.reader.(interface{ Len() int }); ok {
+			return int64(r.Len())
+		}
+		return -1
+	case p.path != "":
+		info, err := os.Stat(p.path)
+		if err != nil {
+			return -1
+		}
+		return info.Size()
+	}
+	return int64(len(p.data))
+}
+
+func filePart(formname, filename string, argv ...string) *formPart {
+	part := &formPart{name: formname, path: filename, filename: filepath.Base(filename)}
+	if len(argv) > 0 && len(argv[0]) > 0 {
+		part.filename = argv[0]
+	}
+	return part
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	sent  int64
+	total int64
+	fn    func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.fn(r.sent, r.total)
+	}
+	return n, err
+}
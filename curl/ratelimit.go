@@ -0,0 +1,185 @@
+package curl
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited 表示 FailFast 模式下请求超出了限流配置。
+var ErrRateLimited = errors.New("http: rate limit exceeded")
+
+// RateLimit 描述一组令牌桶限流和并发限制配置。
+type RateLimit struct {
+	// QPS 为每秒允许发出的请求数，0 表示不限速。
+	QPS float64
+	// Burst 为令牌桶容量，小于 1 时使用 QPS 向上取整且至少为 1。
+	Burst int
+	// MaxInFlight 为同时进行中的请求上限，0 表示不限制；请求在响应体关闭后才会释放名额。
+	MaxInFlight int
+	// FailFast 为 true 时超出限制立即返回 ErrRateLimited，否则等待直到可以发送或请求上下文结束。
+	FailFast bool
+}
+
+// RateLimiter 按客户端整体和按主机对请求限流，通过 Setting.RateLimiter、Client.SetRateLimiter
+// 或 Request.SetRateLimiter 使用，可被多个客户端共享。重试的每次尝试都会重新占用限流额度。
+//
+//	limiter := curl.NewRateLimiter(curl.RateLimit{QPS: 50, MaxInFlight: 20}).
+//	    SetHost("api.weixin.qq.com", curl.RateLimit{QPS: 10, Burst: 5})
+//	client := curl.NewClient("https://api.weixin.qq.com").SetRateLimiter(limiter)
+type RateLimiter struct {
+	mu     sync.RWMutex
+	global *limitBucket
+	hosts  map[string]*limitBucket
+}
+
+// NewRateLimiter 创建限流器，limit 作用于经过该限流器的所有请求，零值表示不做整体限制。
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{global: newLimitBucket(limit), hosts: make(map[string]*limitBucket)}
+}
+
+// SetHost 为指定主机设置单独的限流配置，与整体限制同时生效。host 可以带端口，
+// 不带端口时匹配该主机名的所有端口。
+func (l *RateLimiter) SetHost(host string, limit RateLimit) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hosts[host] = newLimitBucket(limit)
+	return l
+}
+
+func (b *Request) SetRateLimiter(limiter *RateLimiter) *Request {
+	b.setting.RateLimiter = limiter
+	return b
+}
+
+func (c *Client) SetRateLimiter(limiter *RateLimiter) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.RateLimiter = limiter
+	return c
+}
+
+// wrap 在发送前依次占用整体和主机的限流额度，额度在响应体关闭或请求失败后释放。
+func (l *RateLimiter) wrap(next Handler) Handler {
+	return func(req *http.Request) (*http.Response, error) {
+		var releases []func()
+		release := func() {
+			for _, fn := range releases {
+				fn()
+			}
+		}
+		for _, bucket := range l.buckets(req) {
+			fn, err := bucket.acquire(req)
+			if err != nil {
+				release()
+				closeRequestBody(req)
+				return nil, err
+			}
+			releases = append(releases, fn)
+		}
+		resp, err := next(req)
+		if err != nil || resp == nil || resp.Body == nil {
+			release()
+			return resp, err
+		}
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	}
+}
+
+func (l *RateLimiter) buckets(req *http.Request) []*limitBucket {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	buckets := []*limitBucket{l.global}
+	if bucket, ok := l.hosts[req.URL.Host]; ok {
+		buckets = append(buckets, bucket)
+	} else if bucket, ok := l.hosts[req.URL.Hostname()]; ok {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+type limitBucket struct {
+	limiter  *rate.Limiter
+	inflight chan struct{}
+	failFast bool
+}
+
+func newLimitBucket(limit RateLimit) *limitBucket {
+	bucket := &limitBucket{failFast: limit.FailFast}
+	if limit.QPS > 0 {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = max(int(math.Ceil(limit.QPS)), 1)
+		}
+		bucket.limiter = rate.NewLimiter(rate.Limit(limit.QPS), burst)
+	}
+	if limit.MaxInFlight > 0 {
+		bucket.inflight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return bucket
+}
+
+func (b *limitBucket) acquire(req *http.Request) (func(), error) {
+	ctx := req.Context()
+	if b.inflight != nil {
+		if b.failFast {
+			select {
+			case b.inflight <- struct{}{}:
+			default:
+				return nil, ErrRateLimited
+			}
+		} else {
+			select {
+			case b.inflight <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	release := func() {
+		if b.inflight != nil {
+			<-b.inflight
+		}
+	}
+	if b.limiter != nil {
+		var err error
+		if b.failFast {
+			if !b.limiter.Allow() {
+				err = ErrRateLimited
+			}
+		} else {
+			err = b.limiter.Wait(ctx)
+		}
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// closeRequestBody 在请求未发送就被拒绝时关闭请求体，与 RoundTripper 的约定一致，
+// 避免 multipart 等管道请求体的写入协程一直阻塞。
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// releaseBody 在响应体首次关闭时释放限流额度。
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,68 @@
+package curl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  RateLimit
+		host    string
+		limit   RateLimit
+		url     string
+		allowed int
+	}{
+		{"burst", RateLimit{QPS: 1, Burst: 2, FailFast: true}, "", RateLimit{}, "http://a.example.com/", 2},
+		{"burst defaults to qps", RateLimit{QPS: 2.5, FailFast: true}, "", RateLimit{}, "http://a.example.com/", 3},
+		{"host limit", RateLimit{}, "a.example.com", RateLimit{QPS: 1, FailFast: true}, "http://a.example.com:8080/", 1},
+		{"host with port", RateLimit{}, "a.example.com:8080", RateLimit{QPS: 1, FailFast: true}, "http://a.example.com:8080/", 1},
+		{"other host", RateLimit{}, "b.example.com", RateLimit{QPS: 1, FailFast: true}, "http://a.example.com/", 5},
+		{"in flight", RateLimit{MaxInFlight: 2, FailFast: true}, "", RateLimit{}, "http://a.example.com/", 2},
+	}
+	for _, tt := range tests {
+		limiter := NewRateLimiter(tt.global)
+		if tt.host != "" {
+			limiter.SetHost(tt.host, tt.limit)
+		}
+		send := limiter.wrap(statusHandler(http.StatusOK))
+		var allowed int
+		for i := 0; i < 5; i++ {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			_, err := send(req)
+			if err == nil {
+				allowed++
+			} else if !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("%s: request %d error = %v, want ErrRateLimited", tt.name, i, err)
+			}
+		}
+		if allowed != tt.allowed {
+			t.Errorf("%s: allowed %d of 5 requests, want %d", tt.name, allowed, tt.allowed)
+		}
+	}
+}
+
+func TestRateLimiterReleasesOnBodyClose(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{MaxInFlight: 1})
+	send := limiter.wrap(statusHandler(http.StatusOK))
+	req, _ := http.NewRequest("GET", "http://a.example.com/", nil)
+	resp, err := send(req)
+	if err != nil {
+		t.Fatalf("first request error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := send(req.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second request while the body is open error = %v, want context.DeadlineExceeded", err)
+	}
+	_ = resp.Body.Close()
+	_ = resp.Body.Close()
+	if _, err := send(req); err != nil {
+		t.Errorf("request after the body was closed error: %v", err)
+	}
+}
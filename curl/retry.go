@@ -0,0 +1,202 @@
+package curl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy 描述请求失败后的重试策略，通过 Setting.RetryPolicy 或 Request.SetRetryPolicy 设置。
+//
+// 传输错误和 RetryStatus 中的状态码会触发重试，两次尝试之间按指数退避等待，
+// 响应带有 Retry-After 时优先使用它给出的等待时间，但同样不超过 MaxInterval。POST、PATCH 等非幂等请求默认不重试，
+// 除非设置 RetryNonIdempotent 或请求带有 Idempotency-Key 请求头。
+// 请求体通过 http.Request.GetBody 重新生成，无法重新生成请求体时不会重试。
+type RetryPolicy struct {
+	// Retries 为最大重试次数，不含首次请求，-1 表示不限次数。
+	Retries int
+	// InitialInterval 为首次重试前的等待时间。
+	InitialInterval time.Duration
+	// MaxInterval 为单次等待时间上限，包括 Retry-After 给出的等待时间，0 表示不限制。
+	MaxInterval time.Duration
+	// Multiplier 为每次重试等待时间的增长倍数，小于 1 时按 1 处理。
+	Multiplier float64
+	// Jitter 为随机抖动比例，取值 0~1，实际等待时间在 interval*(1±Jitter) 之间。
+	Jitter float64
+	// MaxElapsedTime 为从首次请求开始允许重试的总时长，0 表示不限制。
+	MaxElapsedTime time.Duration
+	// RetryStatus 为需要重试的响应状态码。
+	RetryStatus []int
+	// RetryNonIdempotent 为 true 时非幂等请求也会重试。
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy 返回重试 3 次、100ms 起按 2 倍退避、最长等待 5s，
+// 并对 429、502、503、504 重试的策略。
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Retries:         3,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		RetryStatus:     []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+func (b *Request) SetRetryPolicy(policy *RetryPolicy) *Request {
+	b.setting.RetryPolicy = policy
+	return b
+}
+
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setting.RetryPolicy = policy
+	return c
+}
+
+// Attempts 返回最近一次执行请求的尝试次数，包含首次请求。
+func (b *Request) Attempts() int {
+	return b.attempts
+}
+
+func (b *Request) retryPolicy() *RetryPolicy {
+	if b.setting.RetryPolicy != nil {
+		return b.setting.RetryPolicy
+	}
+	// 兼容 Setting.Retries：只在传输错误时立即重试，不区分请求方法。
+	return &RetryPolicy{Retries: b.setting.Retries, RetryNonIdempotent: true}
+}
+
+func (b *Request) doWithRetry(client *http.Client) (*http.Response, error) {
+	policy := b.retryPolicy()
+	send := b.handler(client)
+	start := time.Now()
+	b.attempts = 0
+	for {
+		if b.attempts > 0 {
+			if err := b.rewindBody(); err != nil {
+				return nil, err
+			}
+		}
+		b.attempts++
+		resp, err := send(b.req)
+		if !policy.allowRetry(b.req, b.attempts) || !policy.retryable(resp, err) {
+			return resp, err
+		}
+		wait := policy.backoff(b.attempts, resp)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+wait > policy.MaxElapsedTime {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+		if err := sleepContext(b.req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (b *Request) rewindBody() error {
+	if b.req.Body == nil || b.req.Body == http.NoBody {
+		return nil
+	}
+	if b.req.GetBody == nil {
+		return errors.New("http: the request body cannot be rewound for retry")
+	}
+	body, err := b.req.GetBody()
+	if err != nil {
+		return err
+	}
+	b.req.Body = body
+	return nil
+}
+
+func (p *RetryPolicy) allowRetry(req *http.Request, attempts int) bool {
+	if p.Retries != -1 && attempts > p.Retries {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if p.RetryNonIdempotent || req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return resp == nil && !errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrCircuitOpen)
+	}
+	for _, status := range p.RetryStatus {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempts int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxInterval > 0 && wait > p.MaxInterval {
+				return p.MaxInterval
+			}
+			return wait
+		}
+	}
+	if p.InitialInterval <= 0 {
+		return 0
+	}
+	multiplier := math.Max(p.Multiplier, 1)
+	interval := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) {
+		interval = float64(p.MaxInterval)
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		interval += interval * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(interval)
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package curl
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got <= 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, %v; want about one minute", future, got, ok)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	retryAfter := func(v string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{v}}}
+	}
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempts int
+		resp     *http.Response
+		want     time.Duration
+	}{
+		{"no interval", RetryPolicy{}, 3, nil, 0},
+		{"first", RetryPolicy{InitialInterval: 100 * time.Millisecond, Multiplier: 2}, 1, nil, 100 * time.Millisecond},
+		{"third", RetryPolicy{InitialInterval: 100 * time.Millisecond, Multiplier: 2}, 3, nil, 400 * time.Millisecond},
+		{"multiplier below one", RetryPolicy{InitialInterval: 100 * time.Millisecond, Multiplier: 0.5}, 4, nil, 100 * time.Millisecond},
+		{
+			"capped",
+			RetryPolicy{InitialInterval: time.Second, Multiplier: 10, MaxInterval: 5 * time.Second},
+			3, nil, 5 * time.Second,
+		},
+		{"retry after", RetryPolicy{InitialInterval: time.Millisecond}, 1, retryAfter("2"), 2 * time.Second},
+		{"retry after capped", RetryPolicy{MaxInterval: time.Second}, 1, retryAfter("120"), time.Second},
+		{"invalid retry after", RetryPolicy{InitialInterval: time.Millisecond}, 1, retryAfter("soon"), time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.backoff(tt.attempts, tt.resp); got != tt.want {
+			t.Errorf("%s: backoff(%d) = %v, want %v", tt.name, tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{InitialInterval: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(1, nil); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("backoff() = %v, want within 50ms..150ms", got)
+		}
+	}
+}
+
+func TestRetryPolicyAllowRetry(t *testing.T) {
+	newRequest := func(method string, body bool, header string) *http.Request {
+		req, _ := http.NewRequest(method, "http://example.com", nil)
+		if body {
+			req.Body = io.NopCloser(strings.NewReader("x"))
+		}
+		if header != "" {
+			req.Header.Set("Idempotency-Key", header)
+		}
+		return req
+	}
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		req      *http.Request
+		attempts int
+		want     bool
+	}{
+		{"get", RetryPolicy{Retries: 2}, newRequest("GET", false, ""), 2, true},
+		{"retries used", RetryPolicy{Retries: 2}, newRequest("GET", false, ""), 3, false},
+		{"unlimited", RetryPolicy{Retries: -1}, newRequest("GET", false, ""), 100, true},
+		{"post", RetryPolicy{Retries: 2}, newRequest("POST", false, ""), 1, false},
+		{"post with key", RetryPolicy{Retries: 2}, newRequest("POST", false, "abc"), 1, true},
+		{"post forced", RetryPolicy{Retries: 2, RetryNonIdempotent: true}, newRequest("POST", false, ""), 1, true},
+		{"body without GetBody", RetryPolicy{Retries: 2}, newRequest("PUT", true, ""), 1, false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.allowRetry(tt.req, tt.attempts); got != tt.want {
+			t.Errorf("%s: allowRetry(%d) = %v, want %v", tt.name, tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"transport error", nil, errors.New("connection reset"), true},
+		{"rate limited", nil, ErrRateLimited, false},
+		{"circuit open", nil, &CircuitOpenError{Key: "example.com"}, false},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"500", &http.Response{StatusCode: http.StatusInternalServerError}, nil, false},
+		{"200", &http.Response{StatusCode: http.StatusOK}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := policy.retryable(tt.resp, tt.err); got != tt.want {
+			t.Errorf("%s: retryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryRewindsBody(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialInterval = time.Millisecond
+	req := Put(srv.URL).SetRetryPolicy(policy).Body("payload")
+	body, err := req.String()
+	if err != nil || body != "ok" {
+		t.Fatalf("String() = %q, %v", body, err)
+	}
+	if req.Attempts() != 3 {
+		t.Errorf("Attempts() = %d, want 3", req.Attempts())
+	}
+	for i, got := range bodies {
+		if got != "payload" {
+			t.Errorf("attempt %d body = %q, want %q", i+1, got, "payload")
+		}
+	}
+}
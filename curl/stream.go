@@ -0,0 +1,280 @@
+package curl
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrStopStream 由流式读取的回调函数返回，表示正常结束读取，EachLine、EachChunk、EachEvent 会返回 nil。
+var ErrStopStream = errors.New("http: stop stream")
+
+// Event 表示一条 text/event-stream 事件。
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Retry 为服务端通过 retry 字段建议的重连间隔，未设置时为 0。
+	Retry time.Duration
+}
+
+// Reconnect 设置 EachEvent 在连接异常断开时的自动重连次数和间隔，times 为 -1 表示不限次数。
+// 重连时会携带 Last-Event-ID 请求头，服务端返回的 retry 字段会覆盖 delay。
+// 服务端正常关闭连接或返回 204 时正常结束，返回非 2xx 状态码时以 *HTTPError 结束，均不会重连。
+func (b *Request) Reconnect(times int, delay time.Duration) *Request {
+	b.reconnects = times
+	b.reconnectDelay = delay
+	return b
+}
+
+// EachLine 逐行读取响应体并回调，行尾的 \r\n 或 \n 会被去掉。
+// 流式读取不受 ReadWriteTimeout 的整体超时限制，需要通过 WithContext 控制取消。
+//
+//	err := curl.Get(url).WithContext(ctx).EachLine(func(line string) error {
+//	    fmt.Println(line)
+//	    return nil
+//	})
+func (b *Request) EachLine(fn func(line string) error) error {
+	reader, closer, err := b.openStream()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	err = readLines(bufio.NewReader(reader), fn)
+	if errors.Is(err, ErrStopStream) {
+		return nil
+	}
+	return b.streamError(err)
+}
+
+// EachChunk 按到达顺序读取响应体并回调，每次最多 size 字节，size 小于等于 0 时使用 32KB。
+// 回调收到的切片会在下一次读取时被复用，需要保留时应自行复制。
+func (b *Request) EachChunk(size int, fn func(chunk []byte) error) error {
+	reader, closer, err := b.openStream()
+	if err != nil {
+		return err
+	}
+	defer closer()
+	if size <= 0 {
+		size = 32 * 1024
+	}
+	buf := make([]byte, size)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				if errors.Is(err, ErrStopStream) {
+					return nil
+				}
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return b.streamError(err)
+		}
+	}
+}
+
+// EachEvent 按 text/event-stream 格式解析响应体，每收到一条完整事件回调一次。
+// 设置 Reconnect 后，连接异常断开时会携带最后收到的事件 ID 重新请求。
+//
+//	err := client.Post("/chat").JSONBody(req).
+//	    WithContext(ctx).Reconnect(3, time.Second).
+//	    EachEvent(func(e curl.Event) error {
+//	        if e.Data == "[DONE]" {
+//	            return curl.ErrStopStream
+//	        }
+//	        fmt.Print(e.Data)
+//	        return nil
+//	    })
+func (b *Request) EachEvent(fn func(event Event) error) error {
+	if b.req.Header.Get("Accept") == "" {
+		b.req.Header.Set("Accept", "text/event-stream")
+	}
+	var (
+		lastID string
+		delay  = b.reconnectDelay
+		times  int
+	)
+	reader, closer, err := b.openStream()
+	for {
+		if err == nil {
+			var received bool
+			err = readEvents(bufio.NewReader(reader), func(event Event) error {
+				received = true
+				lastID = event.ID
+				if event.Retry > 0 {
+					delay = event.Retry
+				}
+				if err := fn(event); err != nil {
+					return callbackError{err}
+				}
+				return nil
+			})
+			closer()
+			var cbErr callbackError
+			if errors.As(err, &cbErr) {
+				if errors.Is(cbErr.err, ErrStopStream) {
+					return nil
+				}
+				return cbErr.err
+			}
+			if err == nil {
+				return nil
+			}
+			if received {
+				times = 0
+			}
+		} else if isStreamStatusError(err) {
+			return err
+		}
+		if b.req.Context().Err() != nil {
+			return b.req.Context().Err()
+		}
+		if b.reconnects != -1 && times >= b.reconnects {
+			return err
+		}
+		times++
+		if err := sleepContext(b.req.Context(), delay); err != nil {
+			return err
+		}
+		if lastID != "" {
+			b.req.Header.Set("Last-Event-ID", lastID)
+		}
+		if err = b.rewindBody(); err != nil {
+			return err
+		}
+		reader, closer, err = b.sendStream()
+	}
+}
+
+// callbackError 用于区分回调函数返回的错误和读取响应体的错误。
+type callbackError struct {
+	err error
+}
+
+func (e callbackError) Error() string {
+	return e.err.Error()
+}
+
+func isStreamStatusError(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr)
+}
+
+func (b *Request) openStream() (io.Reader, func(), error) {
+	b.stream = true
+	resp, err := b.DoRequest()
+	return b.readStream(resp, err)
+}
+
+func (b *Request) sendStream() (io.Reader, func(), error) {
+	resp, err := b.doWithRetry(b.httpClient())
+	return b.readStream(resp, err)
+}
+
+func (b *Request) readStream(resp *http.Response, err error) (io.Reader, func(), error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	closer := func() {
+		_ = resp.Body.Close()
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		closer()
+		return strings.NewReader(""), func() {}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, newHTTPError(b.req, resp, readErrorBody(resp))
+	}
+	if b.setting.Gzip && resp.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			closer()
+			return nil, nil, err
+		}
+		return reader, closer, nil
+	}
+	return resp.Body, closer, nil
+}
+
+// streamError 在请求上下文已取消时返回上下文错误，便于调用方判断 context.Canceled。
+func (b *Request) streamError(err error) error {
+	if err != nil && b.req.Context().Err() != nil {
+		return b.req.Context().Err()
+	}
+	return err
+}
+
+func readLines(reader *bufio.Reader, fn func(line string) error) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 || err == nil {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func readEvents(reader *bufio.Reader, fn func(event Event) error) error {
+	var (
+		event Event
+		data  strings.Builder
+		has   bool
+	)
+	return readLines(reader, func(line string) error {
+		if line == "" {
+			if !has {
+				event.Event = ""
+				return nil
+			}
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			if event.Event == "" {
+				event.Event = "message"
+			}
+			err := fn(event)
+			event = Event{ID: event.ID}
+			data.Reset()
+			has = false
+			return err
+		}
+		if strings.HasPrefix(line, ":") {
+			return nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			has = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				event.ID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				event.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package curl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLines(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\nb\n", []string{"a", "b"}},
+		{"a\r\nb\r\n\r\nc", []string{"a", "b", "", "c"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := readLines(bufio.NewReader(strings.NewReader(tt.input)), func(line string) error {
+			got = append(got, line)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readLines(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{
+			"default message",
+			"data: hello\n\n",
+			[]Event{{Event: "message", Data: "hello"}},
+		},
+		{
+			"multi-line data and fields",
+			"id: 1\nevent: update\ndata: a\ndata:b\nretry: 1500\n\n",
+			[]Event{{ID: "1", Event: "update", Data: "a\nb", Retry: 1500 * time.Millisecond}},
+		},
+		{
+			"comments and crlf",
+			": keep-alive\r\ndata: x\r\n\r\n",
+			[]Event{{Event: "message", Data: "x"}},
+		},
+		{
+			"id is carried over",
+			"id: 7\ndata: a\n\ndata: b\n\n",
+			[]Event{{ID: "7", Event: "message", Data: "a"}, {ID: "7", Event: "message", Data: "b"}},
+		},
+		{
+			"event without data is dropped",
+			"event: ping\n\ndata: a\n\n",
+			[]Event{{Event: "message", Data: "a"}},
+		},
+		{
+			"invalid retry and id with null",
+			"retry: soon\nid: a\x00b\ndata: a\n\n",
+			[]Event{{Event: "message", Data: "a"}},
+		},
+		{
+			"unterminated event is dropped",
+			"data: a\n\ndata: b",
+			[]Event{{Event: "message", Data: "a"}},
+		},
+	}
+	for _, tt := range tests {
+		var got []Event
+		err := readEvents(bufio.NewReader(strings.NewReader(tt.input)), func(event Event) error {
+			got = append(got, event)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: readEvents() = %+v, %v; want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestEachEventReconnect(t *testing.T) {
+	var lastIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(lastIDs) == 1 {
+			w.Header().Set("Content-Length", "100")
+			_, _ = fmt.Fprint(w, "retry: 1\nid: 1\ndata: first\n\n")
+			return
+		}
+		_, _ = fmt.Fprint(w, "id: 2\ndata: second\n\n")
+	}))
+	defer srv.Close()
+
+	var data []string
+	err := Get(srv.URL).Reconnect(1, time.Hour).EachEvent(func(event Event) error {
+		data = append(data, event.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachEvent() error: %v", err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(data, want) {
+		t.Errorf("events = %q, want %q", data, want)
+	}
+	if want := []string{"", "1"}; !reflect.DeepEqual(lastIDs, want) {
+		t.Errorf("Last-Event-ID headers = %q, want %q", lastIDs, want)
+	}
+}
+
+func TestEachEventStop(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "data: a\n\ndata: b\n\n")
+	}))
+	defer srv.Close()
+
+	errBoom := errors.New("boom")
+	tests := []struct {
+		ret     error
+		want    error
+		wantLen int
+	}{
+		{ErrStopStream, nil, 1},
+		{errBoom, errBoom, 1},
+		{nil, nil, 2},
+	}
+	for _, tt := range tests {
+		var n int
+		err := Get(srv.URL).EachEvent(func(Event) error {
+			n++
+			return tt.ret
+		})
+		if !errors.Is(err, tt.want) || n != tt.wantLen {
+			t.Errorf("callback returning %v: EachEvent() = %v after %d events; want %v after %d", tt.ret, err, n, tt.want, tt.wantLen)
+		}
+	}
+}
+
+func TestEachEventStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+
+	err := Get(srv.URL).Reconnect(-1, time.Millisecond).EachEvent(func(Event) error { return nil })
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusGone {
+		t.Errorf("EachEvent() error = %v, want an *HTTPError with status 410", err)
+	}
+}
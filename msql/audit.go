@@ -0,0 +1,462 @@
+package msql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 行变更审计动作。
+const (
+	// AuditInsert 表示插入行。
+	AuditInsert = "insert"
+	// AuditUpdate 表示更新行。
+	AuditUpdate = "update"
+	// AuditDelete 表示删除行。
+	AuditDelete = "delete"
+)
+
+// AuditConfig 表示单张表的行变更审计配置。
+type AuditConfig struct {
+	// PrimaryKey 为用于定位变更行的主键字段，为空时使用 id。
+	PrimaryKey string
+	// Ignore 为不参与字段级差异计算的字段，例如 updated_at。
+	Ignore []string
+	// Sink 接收审计记录，不能为空。
+	Sink AuditSink
+}
+
+// AuditChange 表示单个字段的变更前后值。
+//
+// 值使用与 Params 一致的字符串表示，NULL 记为空字符串。
+type AuditChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// AuditRecord 表示一行数据的一次变更记录。
+type AuditRecord struct {
+	// Alias 为执行写入的数据库别名。
+	Alias string
+	// Table 为发生变更的表名。
+	Table string
+	// Action 为 AuditInsert、AuditUpdate 或 AuditDelete。
+	Action string
+	// PrimaryKey 为变更行的主键值。
+	PrimaryKey string
+	// Actor 为通过 WithAuditActor 写入 context 的操作人。
+	Actor string
+	// Before 为变更前的整行数据，插入时为 nil。
+	Before Params
+	// After 为变更后的整行数据，删除时为 nil。
+	After Params
+	// Changes 为字段级差异，key 为字段名。
+	Changes map[string]AuditChange
+	// Time 为写入执行时间。
+	Time time.Time
+}
+
+// AuditSink 接收行变更审计记录。
+//
+// WriteAudit 在写入所在的事务中调用，tx 即该事务，返回的错误会作为写入方法的错误返回。
+// Builder 未开启事务、由审计自动开启事务时，返回错误会回滚本次写入；
+// Builder 已处于调用方开启的事务中时不会自动回滚，由调用方根据错误决定回滚。
+type AuditSink interface {
+	WriteAudit(tx *sql.Tx, name string, records []AuditRecord) error
+}
+
+// AuditSinkFunc 将普通函数适配为 AuditSink。
+type AuditSinkFunc func(tx *sql.Tx, name string, records []AuditRecord) error
+
+// WriteAudit 调用 f 本身。
+func (f AuditSinkFunc) WriteAudit(tx *sql.Tx, name string, records []AuditRecord) error {
+	return f(tx, name, records)
+}
+
+// auditActorKey 是 context 中保存审计操作人的 key 类型。
+type auditActorKey struct{}
+
+// WithAuditActor 返回携带审计操作人的 context，配合 Builder.WithContext 使用。
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActor 返回 context 中的审计操作人，没有设置时返回空字符串。
+func AuditActor(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+// WithContext 设置当前 Builder 使用的 context。
+//
+// Builder 的查询、写入以及 Begin 开启的事务都会使用该 context，取消或超时后正在执行的 SQL 会返回错误，
+// 事务会被 database/sql 自动回滚；行变更审计还会从中读取 WithAuditActor 设置的操作人。
+// context 不会被 Reset 清空。
+func (m *Builder) WithContext(ctx context.Context) *Builder {
+	m.ctx = ctx
+	return m
+}
+
+// context 返回 WithContext 设置的 context，未设置时返回 context.Background()。
+func (m *Builder) context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// SetAudit 为指定连接上的表开启行变更审计。
+//
+// 开启后，该表上的 Insert、Update、Update2、Delete 以及基于它们的写入方法会在同一事务内
+// 读取受影响行的变更前后数据，计算字段级差异后交给 config.Sink；Builder 未开启事务时会自动开启并提交。
+// 变更前数据使用 select ... for update 读取，变更后数据按主键重新读取，因此修改主键的更新无法得到变更后数据。
+// 表同时开启了 SetEncryption 时，变更前后数据中的加密字段会先解密为明文再计算差异，
+// Sink 需要自行保护审计记录中的明文，例如为审计表的数据字段同样开启加密。
+// dry-run 模式下不会产生审计记录。table 按 Model 传入的第一个表名匹配，不包含表别名。
+//
+// 示例：
+//
+//	err := msql.SetAudit("", "customer_settings", msql.AuditConfig{
+//	    Sink: msql.NewAuditTableSink("audit_logs"),
+//	})
+//	ctx := msql.WithAuditActor(context.Background(), "admin:42")
+//	_, err = msql.Model("customer_settings").WithContext(ctx).
+//	    Where("id", "=", "1").Update(msql.Datas{"plan": "pro"})
+func SetAudit(name, table string, config AuditConfig) error {
+	table = baseTableName(table)
+	if table == "" {
+		return errEmptyTableName
+	}
+	if config.Sink == nil {
+		return errors.New("the audit sink cannot be empty")
+	}
+	if config.PrimaryKey = ToField(config.PrimaryKey); config.PrimaryKey == "" {
+		config.PrimaryKey = "id"
+	}
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		if alias.audits == nil {
+			alias.audits = make(map[string]*AuditConfig)
+		}
+		alias.audits[table] = &config
+		alias.mu.Unlock()
+	})
+}
+
+// RemoveAudit 关闭指定连接上表的行变更审计。
+func RemoveAudit(name, table string) error {
+	table = baseTableName(table)
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		delete(alias.audits, table)
+		alias.mu.Unlock()
+	})
+}
+
+// NewAuditTableSink 返回把审计记录写入审计表的 AuditSink。
+//
+// 审计表需要包含 table_name、action、primary_key、actor、before_data、after_data、changes 和 created_at 字段，
+// 其中 before_data、after_data、changes 写入 JSON 文本，created_at 写入 time.Time。
+// 审计表本身不应再开启审计。
+func NewAuditTableSink(table string) AuditSink {
+	return AuditSinkFunc(func(tx *sql.Tx, name string, records []AuditRecord) error {
+		for _, record := range records {
+			before, err := marshalAuditValue(record.Before)
+			if err != nil {
+				return err
+			}
+			after, err := marshalAuditValue(record.After)
+			if err != nil {
+				return err
+			}
+			changes, err := marshalAuditValue(record.Changes)
+			if err != nil {
+				return err
+			}
+			m := &Builder{table: table, name: name, tx: tx, istx: tx != nil}
+			if _, err := m.Insert(Datas{
+				"table_name":  record.Table,
+				"action":      record.Action,
+				"primary_key": record.PrimaryKey,
+				"actor":       record.Actor,
+				"before_data": before,
+				"after_data":  after,
+				"changes":     changes,
+				"created_at":  record.Time,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// marshalAuditValue 将审计数据编码为 JSON 文本，nil 值返回 nil 以写入 NULL。
+func marshalAuditValue(v any) (any, error) {
+	switch d := v.(type) {
+	case Params:
+		if d == nil {
+			return nil, nil
+		}
+	case map[string]AuditChange:
+		if d == nil {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// auditConfig 返回当前 Builder 表上的审计配置，未开启审计或处于 dry-run 模式时返回 nil。
+func (m *Builder) auditConfig() *AuditConfig {
+	if m.isDryRun() {
+		return nil
+	}
+	alias, ok := lookupDataBase(m.name)
+	if !ok || alias == nil {
+		return nil
+	}
+	table := baseTableName(m.table)
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	return alias.audits[table]
+}
+
+// execAudited 执行 update 或 delete 写入，并在开启审计时记录受影响行的变更。
+//
+// where 和 whereArgs 为写入使用的未渲染 where 子句及其参数，用于在写入前锁定并读取受影响行。
+func (m *Builder) execAudited(action, where string, whereArgs []any, query string, args []any) (int64, error) {
+	audit := m.auditConfig()
+	if audit == nil {
+		return m.execRowsAffected(query, args)
+	}
+	table, err := m.tableName()
+	if err != nil {
+		return 0, err
+	}
+	var rows int64
+	err = m.inAuditTx(func() error {
+		lockQuery := renderParamSeats(m.name, joinSQLParts("select * from", table, m.alias, where, "for update"), 0)
+		before, err := rawValuesContext(m.context(), m.name, lockQuery, m.tx, whereArgs)
+		if err != nil {
+			return err
+		}
+		if err := m.decryptParams(before); err != nil {
+			return err
+		}
+		now := time.Now()
+		if rows, err = m.execRowsAffected(query, args); err != nil {
+			return err
+		}
+		var after []Params
+		if action == AuditUpdate {
+			if after, err = m.auditRowsByKeys(table, audit.PrimaryKey, auditKeys(before, audit.PrimaryKey)); err != nil {
+				return err
+			}
+			if err := m.decryptParams(after); err != nil {
+				return err
+			}
+		}
+		records := m.buildAuditRecords(audit, action, before, after, now)
+		if len(records) == 0 {
+			return nil
+		}
+		return audit.Sink.WriteAudit(m.tx, m.name, records)
+	})
+	if err != nil {
+		m.affect = 0
+		return 0, err
+	}
+	return rows, nil
+}
+
+// insertAudited 执行 insert 写入，并记录插入后的整行数据。
+//
+// 插入后的数据优先按自增 ID 或 data 中的主键重新读取，无法定位时使用 data 本身。
+func (m *Builder) insertAudited(audit *AuditConfig, data Datas, query string, values []any, returning []string) (int64, error) {
+	table, err := m.tableName()
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = m.inAuditTx(func() error {
+		now := time.Now()
+		if id, err = m.execInsert(query, values, returning); err != nil {
+			return err
+		}
+		key := ""
+		if id > 0 {
+			key = fmt.Sprint(id)
+		} else if v, ok := data[audit.PrimaryKey]; ok && !isRawExpr(v) {
+			key = formatAuditValue(v)
+		}
+		var after []Params
+		if key != "" {
+			if after, err = m.auditRowsByKeys(table, audit.PrimaryKey, []string{key}); err != nil {
+				return err
+			}
+		}
+		if len(after) == 0 {
+			row := Params{}
+			for k, v := range data {
+				if !isRawExpr(v) {
+					row[ToField(k)] = formatAuditValue(v)
+				}
+			}
+			after = []Params{row}
+		}
+		if err := m.decryptParams(after); err != nil {
+			return err
+		}
+		return audit.Sink.WriteAudit(m.tx, m.name, m.buildAuditRecords(audit, AuditInsert, nil, after, now))
+	})
+	if err != nil {
+		m.lastid = 0
+		return 0, err
+	}
+	return id, nil
+}
+
+// inAuditTx 在当前事务中执行 fn；Builder 未开启事务时自动开启，并按 fn 结果提交或回滚。
+func (m *Builder) inAuditTx(fn func() error) error {
+	if m.istx {
+		return fn()
+	}
+	if err := m.Begin(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		_ = m.Rollback()
+		return err
+	}
+	return m.Commit()
+}
+
+// auditRowsByKeys 在当前事务中按主键读取整行数据。
+func (m *Builder) auditRowsByKeys(table, primaryKey string, keys []string) ([]Params, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	seats := make([]string, len(keys))
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		seats[i] = paramSeat
+		args[i] = key
+	}
+	query := "select * from " + table + " where " + primaryKey + " in(" + strings.Join(seats, ",") + ")"
+	return rawValuesContext(m.context(), m.name, renderParamSeats(m.name, query, 0), m.tx, args)
+}
+
+// buildAuditRecords 按主键配对变更前后数据，生成包含字段级差异的审计记录。
+//
+// 更新前后没有任何字段差异的行不会生成记录。
+func (m *Builder) buildAuditRecords(audit *AuditConfig, action string, before, after []Params, now time.Time) []AuditRecord {
+	actor := AuditActor(m.ctx)
+	table := baseTableName(m.table)
+	afterByKey := make(map[string]Params, len(after))
+	for _, row := range after {
+		afterByKey[row[audit.PrimaryKey]] = row
+	}
+	newRecord := func(key string, old, cur Params) AuditRecord {
+		return AuditRecord{
+			Alias:      m.name,
+			Table:      table,
+			Action:     action,
+			PrimaryKey: key,
+			Actor:      actor,
+			Before:     old,
+			After:      cur,
+			Changes:    diffAuditRows(old, cur, audit.Ignore),
+			Time:       now,
+		}
+	}
+	records := make([]AuditRecord, 0, len(before)+len(after))
+	switch action {
+	case AuditInsert:
+		for _, row := range after {
+			records = append(records, newRecord(row[audit.PrimaryKey], nil, row))
+		}
+	case AuditDelete:
+		for _, row := range before {
+			records = append(records, newRecord(row[audit.PrimaryKey], row, nil))
+		}
+	default:
+		for _, row := range before {
+			key := row[audit.PrimaryKey]
+			record := newRecord(key, row, afterByKey[key])
+			if len(record.Changes) == 0 {
+				continue
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// diffAuditRows 计算两行数据的字段级差异，old 或 cur 为 nil 时视为所有字段为空。
+func diffAuditRows(old, cur Params, ignore []string) map[string]AuditChange {
+	fields := make(map[string]struct{}, len(old)+len(cur))
+	for k := range old {
+		fields[k] = struct{}{}
+	}
+	for k := range cur {
+		fields[k] = struct{}{}
+	}
+	changes := make(map[string]AuditChange)
+	for field := range fields {
+		if InArray(field, ignore) {
+			continue
+		}
+		if old[field] != cur[field] {
+			changes[field] = AuditChange{Old: old[field], New: cur[field]}
+		}
+	}
+	return changes
+}
+
+// auditKeys 返回数据行中去重后的主键值，并保持稳定顺序。
+func auditKeys(rows []Params, primaryKey string) []string {
+	seen := make(map[string]struct{}, len(rows))
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		key, ok := row[primaryKey]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatAuditValue 将写入值格式化为与查询结果一致的字符串。
+func formatAuditValue(v any) string {
+	switch d := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(d)
+	case time.Time:
+		return d.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(d)
+	}
+}
@@ -0,0 +1,87 @@
+package msql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffAuditRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		old    Params
+		cur    Params
+		ignore []string
+		want   map[string]AuditChange
+	}{
+		{"same", Params{"id": "1", "a": "x"}, Params{"id": "1", "a": "x"}, nil, map[string]AuditChange{}},
+		{"changed", Params{"id": "1", "a": "x"}, Params{"id": "1", "a": "y"}, nil, map[string]AuditChange{"a": {"x", "y"}}},
+		{"ignored", Params{"a": "x", "updated_at": "1"}, Params{"a": "x", "updated_at": "2"}, []string{"updated_at"}, map[string]AuditChange{}},
+		{"insert", nil, Params{"id": "1"}, nil, map[string]AuditChange{"id": {"", "1"}}},
+		{"delete", Params{"id": "1"}, nil, nil, map[string]AuditChange{"id": {"1", ""}}},
+	}
+	for _, tt := range tests {
+		if got := diffAuditRows(tt.old, tt.cur, tt.ignore); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffAuditRows() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditUpdate(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	var records []AuditRecord
+	var sinkErr error
+	err := SetAudit(name, "users", AuditConfig{
+		Ignore: []string{"updated_at"},
+		Sink: AuditSinkFunc(func(tx *sql.Tx, alias string, list []AuditRecord) error {
+			if tx == nil || alias != name {
+				t.Errorf("WriteAudit(tx %v, %q), want a transaction on %q", tx, alias, name)
+			}
+			records = append(records, list...)
+			return sinkErr
+		}),
+	})
+	if err != nil {
+		t.Fatalf("SetAudit() error: %v", err)
+	}
+	columns := []string{"id", "plan", "updated_at"}
+	fake.Expect(`for update$`).WillReturnRows(columns, []any{1, "free", "t1"}, []any{2, "pro", "t1"})
+	fake.Expect(`^select \* from users where id in`).WillReturnRows(columns, []any{1, "pro", "t2"}, []any{2, "pro", "t2"})
+	fake.Expect(`^update`).WillReturnResult(0, 2)
+
+	ctx := WithAuditActor(context.Background(), "admin:42")
+	rows, err := Model("users", name).WithContext(ctx).Where("id", "in", "1,2").Update(Datas{"plan": "pro"})
+	if err != nil || rows != 2 {
+		t.Fatalf("Update() = %d, %v; want 2, nil", rows, err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d audit records, want 1 for the changed row: %+v", len(records), records)
+	}
+	got := records[0]
+	if got.Action != AuditUpdate || got.Table != "users" || got.PrimaryKey != "1" || got.Actor != "admin:42" {
+		t.Errorf("record = %+v", got)
+	}
+	if want := map[string]AuditChange{"plan": {"free", "pro"}}; !reflect.DeepEqual(got.Changes, want) {
+		t.Errorf("changes = %v, want %v", got.Changes, want)
+	}
+	var steps []string
+	for _, q := range fake.Queries() {
+		steps = append(steps, strings.Fields(q.Query)[0])
+	}
+	if want := []string{"BEGIN", "select", "update", "select", "COMMIT"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("driver calls = %v, want %v", steps, want)
+	}
+
+	fake.Reset()
+	fake.Expect(`for update$`).WillReturnRows(columns, []any{1, "pro", "t2"})
+	sinkErr = errors.New("audit table is full")
+	if _, err := Model("users", name).Where("id", "=", "1").Delete(); !errors.Is(err, sinkErr) {
+		t.Fatalf("Delete() error = %v, want the sink error", err)
+	}
+	if q, _ := fake.LastQuery(); q.Query != "ROLLBACK" {
+		t.Errorf("last driver call = %q, want ROLLBACK", q.Query)
+	}
+}
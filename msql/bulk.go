@@ -0,0 +1,388 @@
+package msql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// BulkRowIterator 按顺序提供批量导入的行数据。
+//
+// Next 返回一行字段值，字段顺序与导入列一致；数据读取完毕时返回 io.EOF，返回其它错误会中止导入。
+type BulkRowIterator interface {
+	Next() ([]any, error)
+}
+
+// BulkRowsFunc 将普通函数适配为 BulkRowIterator。
+type BulkRowsFunc func() ([]any, error)
+
+// Next 调用 f 本身。
+func (f BulkRowsFunc) Next() ([]any, error) {
+	return f()
+}
+
+// BulkRowError 表示批量导入中单行数据的错误。
+type BulkRowError struct {
+	// Row 为从 1 开始的数据行号，数据库未给出行号时为 0。
+	Row int
+	// Err 为该行的错误原因。
+	Err error
+}
+
+// Error 返回带行号的错误描述。
+func (e BulkRowError) Error() string {
+	if e.Row > 0 {
+		return "row " + strconv.Itoa(e.Row) + ": " + e.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+// BulkResult 表示一次批量导入的结果。
+type BulkResult struct {
+	// Rows 为数据库报告的导入行数。
+	Rows int64
+	// Errors 为被跳过或被数据库警告的行级错误。
+	Errors []BulkRowError
+}
+
+// bulkHandlerSeq 用于生成进程内唯一的 MySQL LOAD DATA 读取器名称。
+var bulkHandlerSeq atomic.Int64
+
+// BulkLoad 将 rows 中的数据流式批量导入到 table 的 columns 列。
+//
+// 导入在独立事务中执行，rows 返回错误或导入失败时整体回滚。
+// PostgreSQL 别名使用 COPY ... FROM STDIN；MySQL 别名使用 LOAD DATA LOCAL INFILE 和注册的读取器，
+// 服务端需要开启 local_infile，导入后会读取 SHOW WARNINGS 作为行级错误。字段数与 columns 不一致或无法转换的行会被跳过并记录到 Errors。
+// nil 值写入 NULL；table 和 columns 会按驱动规则加引号。
+//
+// 示例：
+//
+//	ret, err := msql.BulkLoad("pg", "orders", []string{"id", "amount"}, msql.BulkRows([][]any{{1, 9.9}, {2, 19.9}}))
+func BulkLoad(name, table string, columns []string, rows BulkRowIterator) (*BulkResult, error) {
+	if baseTableName(table) == "" {
+		return nil, errEmptyTableName
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("the bulk load columns cannot be empty")
+	}
+	if rows == nil {
+		return nil, errors.New("the bulk load rows cannot be nil")
+	}
+	if isPostgres(name) {
+		return bulkLoadPostgres(name, table, columns, rows)
+	}
+	return bulkLoadMysql(name, table, columns, rows)
+}
+
+// BulkLoadCSV 将 CSV 数据流式批量导入到 table。
+//
+// columns 为空时使用 CSV 第一行作为列名，否则所有行都视为数据；CSV 中的空字段按空字符串导入。
+// 其余规则与 BulkLoad 一致。
+//
+// 示例：
+//
+//	f, _ := os.Open("orders.csv")
+//	defer f.Close()
+//	ret, err := msql.BulkLoadCSV("", "orders", nil, f)
+func BulkLoadCSV(name, table string, columns []string, r io.Reader) (*BulkResult, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	if len(columns) == 0 {
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("read csv header: %w", err)
+		}
+		columns = header
+	}
+	return BulkLoad(name, table, columns, BulkCSVRows(reader))
+}
+
+// BulkRows 将内存中的行数据包装为 BulkRowIterator。
+func BulkRows(rows [][]any) BulkRowIterator {
+	index := 0
+	return BulkRowsFunc(func() ([]any, error) {
+		if index >= len(rows) {
+			return nil, io.EOF
+		}
+		index++
+		return rows[index-1], nil
+	})
+}
+
+// BulkCSVRows 将 csv.Reader 包装为 BulkRowIterator，每条记录的字段按字符串导入。
+func BulkCSVRows(r *csv.Reader) BulkRowIterator {
+	return BulkRowsFunc(func() ([]any, error) {
+		record, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		row := make([]any, len(record))
+		for i, v := range record {
+			row[i] = v
+		}
+		return row, nil
+	})
+}
+
+// bulkLoadPostgres 在独立事务中使用 COPY FROM STDIN 导入数据。
+func bulkLoadPostgres(name, table string, columns []string, rows BulkRowIterator) (*BulkResult, error) {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pq.QuoteIdentifier(ToField(column))
+	}
+	query := "COPY " + quotePostgresTable(table) + " (" + strings.Join(quoted, ", ") + ") FROM STDIN"
+	db, err := getExecDB(name, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	result, err := copyBulkRows(tx, query, len(columns), rows)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyBulkRows 逐行执行 COPY 预处理语句，并在最后无参数执行一次以刷新缓冲数据。
+func copyBulkRows(tx *sql.Tx, query string, width int, rows BulkRowIterator) (*BulkResult, error) {
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer func(stmt *sql.Stmt) {
+		_ = stmt.Close()
+	}(stmt)
+	result := &BulkResult{}
+	for line := 1; ; line++ {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		values, err := bulkRowValues(row, width)
+		if err != nil {
+			result.Errors = append(result.Errors, BulkRowError{Row: line, Err: err})
+			continue
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return nil, err
+		}
+	}
+	ret, err := stmt.Exec()
+	if err != nil {
+		return nil, err
+	}
+	result.Rows, _ = ret.RowsAffected()
+	return result, nil
+}
+
+// bulkLoadMysql 使用 LOAD DATA LOCAL INFILE 从注册的读取器导入数据。
+//
+// 写入读取器的内容由独立 goroutine 按行生成，LOAD DATA 和 SHOW WARNINGS 在同一个事务中执行，
+// 读取器出错时已发送的行会随事务回滚。
+func bulkLoadMysql(name, table string, columns []string, rows BulkRowIterator) (*BulkResult, error) {
+	handler := "msql_bulk_" + strconv.FormatInt(bulkHandlerSeq.Add(1), 10)
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteMysqlIdentifier(column)
+	}
+	query := "LOAD DATA LOCAL INFILE 'Reader::" + handler + "' INTO TABLE " + quoteMysqlTable(table) +
+		" CHARACTER SET utf8mb4 FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '\"' ESCAPED BY ''" +
+		" LINES TERMINATED BY '\\n' (" + strings.Join(quoted, ", ") + ")"
+	db, err := getExecDB(name, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	mysql.RegisterReaderHandler(handler, func() io.Reader {
+		return pr
+	})
+	defer mysql.DeregisterReaderHandler(handler)
+
+	result := &BulkResult{}
+	var (
+		wg       sync.WaitGroup
+		writeErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		writer := bufio.NewWriter(pw)
+		for line := 1; ; line++ {
+			row, err := rows.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				writeErr = err
+				_ = pw.CloseWithError(err)
+				return
+			}
+			values, err := bulkRowValues(row, len(columns))
+			if err != nil {
+				result.Errors = append(result.Errors, BulkRowError{Row: line, Err: err})
+				continue
+			}
+			if _, err := writer.WriteString(formatMysqlBulkLine(values)); err != nil {
+				writeErr = err
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+		writeErr = writer.Flush()
+		_ = pw.CloseWithError(writeErr)
+	}()
+
+	ret, err := tx.ExecContext(ctx, query)
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	wg.Wait()
+	if err == nil {
+		// 驱动在读取器出错时可能只结束发送而不返回错误，这里以写入协程的结果为准。
+		err = writeErr
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	result.Rows, _ = ret.RowsAffected()
+	warnings, err := mysqlBulkWarnings(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	result.Errors = append(result.Errors, warnings...)
+	return result, nil
+}
+
+// mysqlBulkRowPattern 用于从 MySQL 警告信息中提取行号。
+var mysqlBulkRowPattern = regexp.MustCompile(`(?i)\brow (\d+)`)
+
+// mysqlBulkWarnings 读取同一事务中 LOAD DATA 产生的警告，并转换为行级错误。
+func mysqlBulkWarnings(ctx context.Context, tx *sql.Tx) ([]BulkRowError, error) {
+	rows, err := tx.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+	var warnings []BulkRowError
+	for rows.Next() {
+		var level, message sql.NullString
+		var code sql.NullInt64
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return nil, err
+		}
+		warning := BulkRowError{Err: fmt.Errorf("%s %d: %s", level.String, code.Int64, message.String)}
+		if match := mysqlBulkRowPattern.FindStringSubmatch(message.String); len(match) == 2 {
+			warning.Row, _ = strconv.Atoi(match[1])
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings, rows.Err()
+}
+
+// bulkRowValues 校验行字段数，并把字段值转换为驱动可接受的值。
+func bulkRowValues(row []any, width int) ([]any, error) {
+	if len(row) != width {
+		return nil, fmt.Errorf("expected %d fields, got %d", width, len(row))
+	}
+	values := make([]any, len(row))
+	for i, v := range row {
+		value, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// formatMysqlBulkLine 将一行值格式化为 LOAD DATA 使用的 CSV 行。
+//
+// 非 NULL 值统一使用双引号包裹并把内部双引号写为两个双引号，NULL 写为未包裹的 NULL。
+func formatMysqlBulkLine(values []any) string {
+	var builder strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		var s string
+		switch d := v.(type) {
+		case nil:
+			builder.WriteString("NULL")
+			continue
+		case []byte:
+			s = string(d)
+		case bool:
+			s = "0"
+			if d {
+				s = "1"
+			}
+		case time.Time:
+			s = d.Format("2006-01-02 15:04:05.999999")
+		default:
+			s = fmt.Sprint(d)
+		}
+		builder.WriteByte('"')
+		builder.WriteString(strings.ReplaceAll(s, `"`, `""`))
+		builder.WriteByte('"')
+	}
+	builder.WriteByte('\n')
+	return builder.String()
+}
+
+// quotePostgresTable 为可能带 schema 前缀的 PostgreSQL 表名逐段加引号。
+func quotePostgresTable(table string) string {
+	parts := strings.Split(baseTableName(table), ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(ToField(part))
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteMysqlTable 为可能带库名前缀的 MySQL 表名逐段加反引号。
+func quoteMysqlTable(table string) string {
+	parts := strings.Split(baseTableName(table), ".")
+	for i, part := range parts {
+		parts[i] = quoteMysqlIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteMysqlIdentifier 为 MySQL 标识符加反引号并转义其中的反引号。
+func quoteMysqlIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(ToField(name), "`", "``") + "`"
+}
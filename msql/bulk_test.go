@@ -0,0 +1,102 @@
+package msql
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatMysqlBulkLine(t *testing.T) {
+	tests := []struct {
+		values []any
+		want   string
+	}{
+		{[]any{int64(1), "tom"}, "\"1\",\"tom\"\n"},
+		{[]any{nil, "a\"b"}, "NULL,\"a\"\"b\"\n"},
+		{[]any{true, false, []byte("x,y")}, "\"1\",\"0\",\"x,y\"\n"},
+		{[]any{time.Date(2024, 5, 6, 7, 8, 9, 500000000, time.UTC)}, "\"2024-05-06 07:08:09.5\"\n"},
+		{[]any{"NULL"}, "\"NULL\"\n"},
+	}
+	for _, tt := range tests {
+		if got := formatMysqlBulkLine(tt.values); got != tt.want {
+			t.Errorf("formatMysqlBulkLine(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestBulkRowValues(t *testing.T) {
+	tests := []struct {
+		row     []any
+		width   int
+		want    []any
+		wantErr bool
+	}{
+		{[]any{1, "a", nil}, 3, []any{int64(1), "a", nil}, false},
+		{[]any{int8(2), 1.5}, 2, []any{int64(2), 1.5}, false},
+		{[]any{1}, 2, nil, true},
+		{[]any{struct{}{}}, 1, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := bulkRowValues(tt.row, tt.width)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("bulkRowValues(%v, %d) error = %v, want error %v", tt.row, tt.width, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bulkRowValues(%v, %d) = %#v, want %#v", tt.row, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteBulkTable(t *testing.T) {
+	tests := []struct {
+		table    string
+		mysql    string
+		postgres string
+	}{
+		{"orders", "`orders`", `"orders"`},
+		{"shop.orders o", "`shop`.`orders`", `"shop"."orders"`},
+	}
+	for _, tt := range tests {
+		if got := quoteMysqlTable(tt.table); got != tt.mysql {
+			t.Errorf("quoteMysqlTable(%q) = %s, want %s", tt.table, got, tt.mysql)
+		}
+		if got := quotePostgresTable(tt.table); got != tt.postgres {
+			t.Errorf("quotePostgresTable(%q) = %s, want %s", tt.table, got, tt.postgres)
+		}
+	}
+	if got := quoteMysqlIdentifier("a`b"); got != "`a``b`" {
+		t.Errorf("quoteMysqlIdentifier(a`b) = %s", got)
+	}
+}
+
+func TestBulkRows(t *testing.T) {
+	rows := BulkRows([][]any{{1}, {2}})
+	for _, want := range []any{1, 2} {
+		row, err := rows.Next()
+		if err != nil || !reflect.DeepEqual(row, []any{want}) {
+			t.Fatalf("Next() = %v, %v; want [%v]", row, err, want)
+		}
+	}
+	if _, err := rows.Next(); err != io.EOF {
+		t.Errorf("Next() at the end error = %v, want io.EOF", err)
+	}
+}
+
+func TestBulkLoadInvalid(t *testing.T) {
+	tests := []struct {
+		table   string
+		columns []string
+		rows    BulkRowIterator
+	}{
+		{"", []string{"id"}, BulkRows(nil)},
+		{"orders", nil, BulkRows(nil)},
+		{"orders", []string{"id"}, nil},
+	}
+	for _, tt := range tests {
+		if _, err := BulkLoad("", tt.table, tt.columns, tt.rows); err == nil {
+			t.Errorf("BulkLoad(%q, %v) returned no error", tt.table, tt.columns)
+		}
+	}
+}
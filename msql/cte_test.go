@@ -0,0 +1,82 @@
+package msql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithArgsOrder(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{
+			DriverMysql,
+			"with active as (select user_id from orders where status=? and amount>?) " +
+				"select * from active a where a.user_id>? order by a.user_id desc",
+		},
+		{
+			DriverPostgres,
+			"with active as (select user_id from orders where status=$1 and amount>$2) " +
+				"select * from active a where a.user_id>$3 order by a.user_id desc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, tt.driver)
+			active := Model("orders", name).Field("user_id").Where("status", "=", "paid").Where("amount", ">", "100")
+			_, err := Model("active a", name).With("active", active).Where("a.user_id", ">", "7").Order("a.user_id desc").Select()
+			if err != nil {
+				t.Fatalf("Select() error: %v", err)
+			}
+			q, _ := fake.LastQuery()
+			if q.Query != tt.want {
+				t.Errorf("query = %q\nwant    %q", q.Query, tt.want)
+			}
+			if want := []any{"paid", "100", "7"}; !reflect.DeepEqual(q.Args, want) {
+				t.Errorf("args = %v, want %v", q.Args, want)
+			}
+		})
+	}
+}
+
+func TestWithRecursiveArgsOrder(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverPostgres)
+	anchor := Model("categories", name).Field("id, parent_id").Where("id", "=", "1")
+	recursive := Model("categories c", name).Field("c.id, c.parent_id").
+		Join("tree t", "c.parent_id = t.id", "inner").Where("c.status", "=", "on")
+	plain := Model("tags", name).Field("id").Where("kind", "=", "hot")
+	_, err := Model("tree", name).WithRecursive("tree(id, parent_id)", anchor, recursive).
+		With("hot", plain).Where("id", "!=", "9").Select()
+	if err != nil {
+		t.Fatalf("Select() error: %v", err)
+	}
+	q, _ := fake.LastQuery()
+	want := "with recursive tree(id, parent_id) as (select id, parent_id from categories where id=$1 union all " +
+		"select c.id, c.parent_id from categories c inner join tree t on c.parent_id = t.id where c.status=$2), " +
+		"hot as (select id from tags where kind=$3) select * from tree where id!=$4"
+	if q.Query != want {
+		t.Errorf("query = %q\nwant    %q", q.Query, want)
+	}
+	if want := []any{"1", "on", "hot", "9"}; !reflect.DeepEqual(q.Args, want) {
+		t.Errorf("args = %v, want %v", q.Args, want)
+	}
+}
+
+func TestWithIgnoresInvalidInput(t *testing.T) {
+	tests := []struct {
+		label string
+		name  string
+		query *Builder
+	}{
+		{"empty name", " ", Model("orders")},
+		{"nil query", "active", nil},
+		{"no table", "active", Model("")},
+	}
+	for _, tt := range tests {
+		m := Model("users").With(tt.name, tt.query)
+		if got := m.getWith(); got != "" {
+			t.Errorf("%s: getWith() = %q, want empty", tt.label, got)
+		}
+	}
+}
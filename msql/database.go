@@ -1,6 +1,7 @@
 package msql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -21,6 +22,11 @@ import (
 //	err := msql.RegisterDataBase("default", "user:pass@tcp(127.0.0.1:3306)/demo")
 //	err = msql.RegisterDataBase("pg", conn, msql.DriverPostgres)
 func RegisterDataBase(name, conn string, driverName ...string) error {
+	return registerDataBase(name, conn, nil, driverName...)
+}
+
+// registerDataBase 注册数据库连接，session 非空时为每个新建物理连接执行会话初始化。
+func registerDataBase(name, conn string, session *SessionInit, driverName ...string) error {
 	var emptyName bool
 	if name == "" {
 		emptyName = true
@@ -40,6 +46,9 @@ func RegisterDataBase(name, conn string, driverName ...string) error {
 		idle: 25,
 		db:   nil,
 	}
+	if session != nil && (len(session.Statements) > 0 || session.Func != nil) {
+		alias.session = session
+	}
 	if err := sqlOpen(alias, driverName...); err != nil {
 		return err
 	}
@@ -60,15 +69,33 @@ func GetDB(name string) (*sql.DB, error) {
 	return nil, errors.New("the database alias does not exist")
 }
 
+// GetDriver 返回已注册连接使用的驱动名，即 DriverMysql 或 DriverPostgres。
+//
+// name 为空时使用 default 连接。
+func GetDriver(name string) (string, error) {
+	alias, err := getDB(name)
+	if err != nil {
+		return "", err
+	}
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	return alias.driver, nil
+}
+
 // Begin 基于指定连接开启原生 database/sql 事务。
 //
 // name 为空时使用 default 连接。链式 Builder 事务通常使用 Model(...).Begin()。
 func Begin(name string) (*sql.Tx, error) {
+	return beginTx(context.Background(), name)
+}
+
+// beginTx 使用 ctx 开启事务，ctx 结束时 database/sql 会自动回滚该事务。
+func beginTx(ctx context.Context, name string) (*sql.Tx, error) {
 	alias, err := getDB(name)
 	if err != nil {
 		return nil, err
 	}
-	return alias.db.Begin()
+	return alias.db.BeginTx(ctx, nil)
 }
 
 // RawValues 执行原始查询 SQL，并将结果按 []Params 返回。
@@ -80,36 +107,69 @@ func Begin(name string) (*sql.Tx, error) {
 //
 //	rows, err := msql.RawValues("", "select id,name from users where id=?", nil, 1)
 func RawValues(name, query string, tx *sql.Tx, args ...any) ([]Params, error) {
-	db, err := getExecDB(name, query, tx, args)
+	return rawValuesContext(context.Background(), name, query, tx, args)
+}
+
+// rawValuesContext 使用 ctx 执行原始查询 SQL，慢查询时触发自动 explain。
+func rawValuesContext(ctx context.Context, name, query string, tx *sql.Tx, args []any) ([]Params, error) {
+	start := time.Now()
+	list, err := queryRawValues(ctx, name, query, tx, args)
 	if err != nil {
 		return nil, err
 	}
+	explainSlowQuery(name, query, tx, args, start)
+	return list, nil
+}
+
+// queryRawValues 执行原始查询 SQL，并将结果按 []Params 返回。
+func queryRawValues(ctx context.Context, name, query string, tx *sql.Tx, args []any) ([]Params, error) {
+	list := make([]Params, 0)
+	err := eachRawRow(ctx, name, query, tx, args, func(_ []string, item Params) error {
+		list = append(list, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// eachRawRow 执行原始查询 SQL，并逐行把结果转换为 Params 交给 fn，不会缓存全部结果。
+//
+// cols 为按查询顺序排列的结果字段名；fn 返回错误时停止读取并返回该错误。
+func eachRawRow(ctx context.Context, name, query string, tx *sql.Tx, args []any, fn func(cols []string, item Params) error) error {
+	db, err := getExecDB(name, query, tx, args)
+	if err != nil {
+		return err
+	}
 	var rows *sql.Rows
-	if tx == nil {
-		rows, err = db.Query(query, args...)
+	if stmt, release := cachedStmt(ctx, name, db, tx, query); stmt != nil {
+		defer release()
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else if tx == nil {
+		rows, err = db.QueryContext(ctx, query, args...)
 	} else {
-		rows, err = tx.Query(query, args...)
+		rows, err = tx.QueryContext(ctx, query, args...)
 	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func(rows *sql.Rows) {
 		_ = rows.Close()
 	}(rows)
-	cols, err2 := rows.Columns()
-	if err2 != nil {
-		return nil, err2
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
 	}
-	list := make([]Params, 0)
+	row := make([]any, len(cols))
 	for rows.Next() {
-		row := make([]any, len(cols))
 		for i := range row {
 			row[i] = &sql.NullString{}
 		}
 		if err := rows.Scan(row...); err != nil {
-			return nil, err
+			return err
 		}
-		item := Params{}
+		item := make(Params, len(cols))
 		for i, v := range row {
 			if s, ok := v.(*sql.NullString); ok {
 				item[cols[i]] = s.String
@@ -117,12 +177,11 @@ func RawValues(name, query string, tx *sql.Tx, args ...any) ([]Params, error) {
 				item[cols[i]] = ""
 			}
 		}
-		list = append(list, item)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+		if err := fn(cols, item); err != nil {
+			return err
+		}
 	}
-	return list, nil
+	return rows.Err()
 }
 
 // RawExec 执行原始写入 SQL，并返回 sql.Result。
@@ -134,14 +193,23 @@ func RawValues(name, query string, tx *sql.Tx, args ...any) ([]Params, error) {
 //
 //	ret, err := msql.RawExec("", "update users set name=? where id=?", nil, "tom", 1)
 func RawExec(name, query string, tx *sql.Tx, args ...any) (sql.Result, error) {
+	return rawExecContext(context.Background(), name, query, tx, args)
+}
+
+// rawExecContext 使用 ctx 执行原始写入 SQL。
+func rawExecContext(ctx context.Context, name, query string, tx *sql.Tx, args []any) (sql.Result, error) {
 	db, err := getExecDB(name, query, tx, args)
 	if err != nil {
 		return nil, err
 	}
+	if stmt, release := cachedStmt(ctx, name, db, tx, query); stmt != nil {
+		defer release()
+		return stmt.ExecContext(ctx, args...)
+	}
 	if tx == nil {
-		return db.Exec(query, args...)
+		return db.ExecContext(ctx, query, args...)
 	}
-	return tx.Exec(query, args...)
+	return tx.ExecContext(ctx, query, args...)
 }
 
 // SetConnMaxLifetime 设置指定数据库连接的最大生命周期。
@@ -180,6 +248,25 @@ func SetDebug(name string, dev bool) error {
 	})
 }
 
+// SetSlowQueryExplain 开启或关闭指定连接的慢查询自动 explain。
+//
+// threshold 大于 0 时，RawValues 及基于它的 Builder 查询耗时达到 threshold 后，
+// 会对同一条 select SQL 执行 explain，并把结果和是否存在全表扫描交给 handler；
+// handler 为空时输出单行慢查询日志。threshold 小于等于 0 时关闭该功能。
+// explain 和 handler 在独立 goroutine 中执行，不阻塞原查询返回，handler 可能被并发调用。
+// 事务内的慢查询只记录不执行 explain，SlowQuery.ExplainErr 为 ErrExplainInTx。
+//
+// 示例：
+//
+//	err := msql.SetSlowQueryExplain("", 200*time.Millisecond, func(q msql.SlowQuery) {
+//	    if q.Plan != nil && q.Plan.FullScan { logs.Warning("full scan: %s", q.Query) }
+//	})
+func SetSlowQueryExplain(name string, threshold time.Duration, handler func(SlowQuery)) error {
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		setAliasSlowQuery(alias, threshold, handler)
+	})
+}
+
 // CloseAllRegDataBase 关闭所有已注册数据库连接，并清空注册表。
 //
 // 如果多个连接关闭失败，会将错误合并后返回。
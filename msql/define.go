@@ -1,6 +1,7 @@
 package msql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"sync"
@@ -26,6 +27,12 @@ type Column map[string]Params
 // Datas 表示写入或更新数据，key 为字段名，value 为字段值。
 type Datas map[string]any
 
+// RawExpr 表示 Datas 中原样拼接的 SQL 表达式及其绑定参数，应通过 Raw 创建。
+type RawExpr struct {
+	expr string
+	args []any
+}
+
 // 事务状态错误。
 var (
 	// TxE0 表示事务尚未开始或已经结束。
@@ -36,6 +43,8 @@ var (
 
 var errEmptyTableName = errors.New("the table name cannot be empty")
 
+var errStmtCacheDisabled = errors.New("the statement cache is disabled")
+
 // SQL 渲染和日志常量。
 const (
 	// paramSeat 是包内构造 SQL 时使用的临时占位符，执行前会转换为具体驱动的占位符。
@@ -44,6 +53,8 @@ const (
 	sqlLogQueryMaxRunes = 0
 	// sqlLogArgMaxRunes 控制调试日志中单个参数的最大 rune 数。
 	sqlLogArgMaxRunes = 32
+	// dryRunStatementsMax 控制别名级 dry-run 记录最多保留的条数，超出后丢弃最早的记录。
+	dryRunStatementsMax = 1000
 )
 
 // Builder 保存一次表级链式 SQL 构造和执行过程中的临时状态。
@@ -56,6 +67,9 @@ const (
 // 并发复用，也不应在使用后复制。需要并发构造 SQL 时，应为每条调用链单独创建 Builder。
 type Builder struct {
 	name        string
+	with        []string
+	withArgs    []any
+	recursive   bool
 	field       []string
 	fieldArgs   []any
 	table       string
@@ -77,6 +91,10 @@ type Builder struct {
 	offset      int
 	istx        bool
 	tx          *sql.Tx
+	dryRun      bool
+	statements  []DryRunStatement
+	ctx         context.Context
+	schema      string
 }
 
 // dataBase 保存单个已注册数据库连接及其连接池配置。
@@ -90,4 +108,22 @@ type dataBase struct {
 	idle   int
 	db     *sql.DB
 	dev    bool
+	// slow 为自动 explain 的慢查询阈值，0 表示关闭。
+	slow time.Duration
+	// slowHandler 接收慢查询分析结果，为空时输出单行日志。
+	slowHandler func(SlowQuery)
+	// dryRun 表示该别名下的 Builder 写入只渲染 SQL，不实际执行。
+	dryRun bool
+	// statements 保存别名级 dry-run 写入 SQL 记录，最多保留 dryRunStatementsMax 条。
+	statements []DryRunStatement
+	// audits 保存按表名开启的行变更审计配置。
+	audits map[string]*AuditConfig
+	// session 为新建物理连接后执行的会话初始化配置，为空时直接使用 sql.Open。
+	session *SessionInit
+	// stmts 为预处理语句缓存，为空表示从未开启。
+	stmts *stmtCache
+	// encryptions 保存按表名开启的字段级加密配置。
+	encryptions map[string]*encryptionState
+	// idHooks 保存按表名开启的主键自动生成配置。
+	idHooks map[string]*idHook
 }
@@ -4,8 +4,10 @@
 //
 // WhereIn、WhereNotIn、WhereBetween、WhereNotBetween、WhereLike、WhereNotLike 和 WhereFindInSet
 // 适合常见条件的类型化参数绑定；复杂表达式可使用 WhereRaw 或 WhereOrRaw。
+// JSON 字段可使用 WhereJSON、WhereJSONContains 和 UpdateJSON，包内会按 MySQL JSON 或 PostgreSQL jsonb 渲染函数和运算符。
+// 公共表表达式可使用 With 和 WithRecursive 追加，CTE 参数会排在主查询参数之前统一编号。
 //
-// 原始 SQL 入口包括 Field、Join、WhereRaw、WhereOrRaw、Having、Order、Update2、RawValues 和 RawExec。
+// 原始 SQL 入口包括 Field、Join、WhereRaw、WhereOrRaw、Having、Order、Update2、Raw、RawValues 和 RawExec。
 //
 // 这些入口仍会保留调用方传入的 SQL 片段；PostgreSQL 下绑定 raw 片段参数时应使用 $1、$2 等占位符，? 会按 SQL 原文保留。
 // raw 片段中的 $n 仅表示一个待绑定参数位置，不表示参数复用；每出现一个 $n 就必须按出现顺序传入一个有实际意义的参数。
@@ -22,4 +24,7 @@
 //
 // Model 或 Table 传入空表名时不会立即返回错误；后续需要表名的查询、写入和表结构检查方法会返回空表名错误。
 // BuildSqlPro 和 BuildSql 无法返回 error，空表名时会返回空 SQL。
+//
+// 单元测试中可使用 RegisterFakeDataBase 注册内存假驱动别名，断言 Builder 最终生成的 SQL 和参数，
+// 并为匹配的 SQL 预设返回结果或错误，无需连接真实数据库。
 package msql
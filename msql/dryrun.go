@@ -0,0 +1,127 @@
+package msql
+
+import "time"
+
+// DryRunStatement 表示 dry-run 模式下已渲染但未执行的一条写入 SQL。
+type DryRunStatement struct {
+	// Alias 为 Builder 使用的数据库别名。
+	Alias string
+	// Table 为 Builder 的表名。
+	Table string
+	// Query 为使用当前驱动占位符渲染后的可执行 SQL。
+	Query string
+	// Args 为按顺序排列的绑定参数。
+	Args []any
+	// Debug 为代入参数后的调试 SQL，仅用于展示。
+	Debug string
+	// Time 为渲染该 SQL 的时间。
+	Time time.Time
+}
+
+// DryRun 开启或关闭当前 Builder 的 dry-run 模式。
+//
+// dry-run 模式下 Insert、Update、Update2、Delete 以及基于它们的写入方法只渲染最终 SQL 和参数，
+// 不会执行，返回的 ID 和影响行数均为 0；渲染结果可通过 GetDryRunStatements 或 GetLastSql 读取。
+// 别名通过 SetDryRun 开启 dry-run 时，无论 Builder 是否开启，写入都不会执行。
+// 查询方法不受 dry-run 影响。
+//
+// 示例：
+//
+//	m := msql.Model("users").DryRun(true)
+//	_, err := m.Where("status", "=", "expired").Delete()
+//	for _, stmt := range m.GetDryRunStatements() {
+//	    fmt.Println(stmt.Debug)
+//	}
+func (m *Builder) DryRun(enable bool) *Builder {
+	m.dryRun = enable
+	return m
+}
+
+// GetDryRunStatements 返回当前 Builder 在 dry-run 模式下记录的写入 SQL。
+func (m *Builder) GetDryRunStatements() []DryRunStatement {
+	statements := make([]DryRunStatement, len(m.statements))
+	copy(statements, m.statements)
+	return statements
+}
+
+// SetDryRun 开启或关闭指定连接的 dry-run 模式。
+//
+// 开启后，使用该别名的所有 Builder 写入都只渲染 SQL 并记录到别名级审计记录中，不会执行；
+// RawExec 等原始 SQL 入口不受影响。name 为空时使用 default 连接。
+func SetDryRun(name string, dryRun bool) error {
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		alias.dryRun = dryRun
+		alias.mu.Unlock()
+	})
+}
+
+// GetDryRunStatements 返回指定连接记录的 dry-run 写入 SQL。
+//
+// 记录同时包含 Builder.DryRun 和 SetDryRun 两种方式拦截的写入，最多保留最近 1000 条。
+func GetDryRunStatements(name string) ([]DryRunStatement, error) {
+	alias, err := getDB(name)
+	if err != nil {
+		return nil, err
+	}
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	statements := make([]DryRunStatement, len(alias.statements))
+	copy(statements, alias.statements)
+	return statements, nil
+}
+
+// ClearDryRunStatements 清空指定连接记录的 dry-run 写入 SQL。
+func ClearDryRunStatements(name string) error {
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		alias.statements = nil
+		alias.mu.Unlock()
+	})
+}
+
+// isDryRun 判断当前 Builder 或其数据库别名是否开启了 dry-run 模式。
+func (m *Builder) isDryRun() bool {
+	if m.dryRun {
+		return true
+	}
+	alias, ok := lookupDataBase(m.name)
+	if !ok || alias == nil {
+		return false
+	}
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	return alias.dryRun
+}
+
+// recordDryRun 在 dry-run 模式下记录写入 SQL，并返回是否应跳过实际执行。
+//
+// query 应为已经渲染为驱动占位符的可执行 SQL。
+func (m *Builder) recordDryRun(query string, args []any) bool {
+	if !m.isDryRun() {
+		return false
+	}
+	stmt := DryRunStatement{
+		Alias: m.name,
+		Table: m.table,
+		Query: query,
+		Args:  args,
+		Debug: renderDebugParamSeats(query, args),
+		Time:  time.Now(),
+	}
+	m.statements = append(m.statements, stmt)
+	if alias, ok := lookupDataBase(m.name); ok && alias != nil {
+		appendAliasDryRunStatement(alias, stmt)
+	}
+	return true
+}
+
+// appendAliasDryRunStatement 追加别名级 dry-run 记录，并丢弃超出上限的最早记录。
+func appendAliasDryRunStatement(alias *dataBase, stmt DryRunStatement) {
+	alias.mu.Lock()
+	defer alias.mu.Unlock()
+	alias.statements = append(alias.statements, stmt)
+	if over := len(alias.statements) - dryRunStatementsMax; over > 0 {
+		alias.statements = append([]DryRunStatement(nil), alias.statements[over:]...)
+	}
+}
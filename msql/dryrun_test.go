@@ -0,0 +1,88 @@
+package msql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverPostgres)
+	m := Model("users", name).DryRun(true)
+	tests := []struct {
+		run   func() (int64, error)
+		query string
+		args  []any
+		debug string
+	}{
+		{
+			func() (int64, error) { return m.Insert(Datas{"name": "tom"}) },
+			"insert into users (name) values ($1)",
+			[]any{"tom"},
+			"insert into users (name) values ('tom')",
+		},
+		{
+			func() (int64, error) { return m.Where("id", "=", "1").Update(Datas{"name": "bob"}) },
+			"update users set name = $1 where id=$2",
+			[]any{"bob", "1"},
+			"update users set name = 'bob' where id='1'",
+		},
+		{
+			func() (int64, error) { return m.Where("status", "=", "expired").Delete() },
+			"delete from users where status=$1",
+			[]any{"expired"},
+			"delete from users where status='expired'",
+		},
+	}
+	for i, tt := range tests {
+		n, err := tt.run()
+		if err != nil || n != 0 {
+			t.Fatalf("write %d = %d, %v; want 0, nil", i, n, err)
+		}
+		statements := m.GetDryRunStatements()
+		if len(statements) != i+1 {
+			t.Fatalf("write %d: %d statements recorded, want %d", i, len(statements), i+1)
+		}
+		stmt := statements[i]
+		if stmt.Alias != name || stmt.Table != "users" || stmt.Query != tt.query || stmt.Debug != tt.debug {
+			t.Errorf("write %d: statement = %+v, want query %q and debug %q", i, stmt, tt.query, tt.debug)
+		}
+		if !reflect.DeepEqual(stmt.Args, tt.args) {
+			t.Errorf("write %d: args = %#v, want %#v", i, stmt.Args, tt.args)
+		}
+	}
+	if got := len(fake.Queries()); got != 0 {
+		t.Errorf("dry-run sent %d queries to the driver, want none", got)
+	}
+}
+
+func TestSetDryRun(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	if err := SetDryRun(name, true); err != nil {
+		t.Fatalf("SetDryRun() error: %v", err)
+	}
+	if _, err := Model("users", name).Where("id", "=", "1").Increment("count", 1); err != nil {
+		t.Fatalf("Increment() error: %v", err)
+	}
+	if _, err := Model("users", name).Select(); err != nil {
+		t.Fatalf("Select() error: %v", err)
+	}
+	statements, _ := GetDryRunStatements(name)
+	if len(statements) != 1 || statements[0].Query != "update users set count = count + ? where id=?" {
+		t.Errorf("alias statements = %+v, want the increment", statements)
+	}
+	if queries := fake.Queries(); len(queries) != 1 || queries[0].Exec {
+		t.Errorf("driver calls = %+v, want only the select", queries)
+	}
+
+	_ = ClearDryRunStatements(name)
+	_ = SetDryRun(name, false)
+	if _, err := Model("users", name).Where("id", "=", "1").Delete(); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if statements, _ := GetDryRunStatements(name); len(statements) != 0 {
+		t.Errorf("alias statements after clearing = %+v, want none", statements)
+	}
+	if q, _ := fake.LastQuery(); !q.Exec {
+		t.Errorf("last driver call = %+v, want the delete to run", q)
+	}
+}
@@ -0,0 +1,378 @@
+package msql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// encryptPrefix 为加密字段密文的固定前缀，完整格式为 enc:v1:<key id>:<base64(nonce|ciphertext)>。
+const encryptPrefix = "enc:v1:"
+
+// EncryptionConfig 表示单表的字段级加密配置。
+type EncryptionConfig struct {
+	// Keys 为 AES 密钥表，key 为密钥 ID，value 为 16、24 或 32 字节密钥；密钥 ID 不能包含冒号。
+	Keys map[string][]byte
+	// CurrentKey 为新写入数据使用的密钥 ID，其余密钥只用于解密和等值查询，以支持密钥轮换。
+	CurrentKey string
+	// Columns 为需要加密的字段及其查询方式。
+	Columns map[string]EncryptedColumn
+}
+
+// EncryptedColumn 表示单个加密字段的配置。
+type EncryptedColumn struct {
+	// Deterministic 为 true 时，同一密钥下相同明文得到相同密文，Where 等值条件可直接匹配密文。
+	Deterministic bool
+	// BlindIndex 为保存盲索引的字段名，非空时写入会同步写入明文的 HMAC-SHA256，
+	// Where 等值条件改为匹配该字段，密文本身仍使用随机 nonce。
+	BlindIndex string
+}
+
+// SetEncryption 为指定连接上的表开启字段级加密。
+//
+// 开启后，该表上的 Insert、Update 会把 Columns 中字段的值使用 AES-GCM 加密后写入，nil 值保持 NULL，
+// 其盲索引字段同时写为 NULL；Select、Find、Value、Paginate 等 Builder 查询会按结果字段名
+// 自动解密 Columns 字段中带密文前缀的值，使用 as 重命名的字段不会解密；
+// 旧密钥加密的数据在 Keys 中保留对应密钥即可继续解密，重新写入时使用 CurrentKey。
+// Deterministic 或 BlindIndex 字段上的 Where(field, "=", v)、Where(field, "!=", v)、in/not in 条件以及
+// WhereIn、WhereNotIn 会改写为按全部密钥计算出的密文或盲索引集合匹配；其余条件和随机加密字段无法按明文查询。
+// 加密字段不能使用 Raw 表达式写入，Update2 片段中出现加密字段名或 UpdateJSON 更新加密字段时会返回错误。
+// table 按 Model 传入的第一个表名匹配，不包含表别名。
+//
+// 示例：
+//
+//	err := msql.SetEncryption("", "users", msql.EncryptionConfig{
+//	    Keys:       map[string][]byte{"2024": oldKey, "2025": newKey},
+//	    CurrentKey: "2025",
+//	    Columns: map[string]msql.EncryptedColumn{
+//	        "phone":      {BlindIndex: "phone_index"},
+//	        "api_secret": {},
+//	    },
+//	})
+//	user, err := msql.Model("users").Where("phone", "=", "13800000000").Find()
+func SetEncryption(name, table string, config EncryptionConfig) error {
+	table = baseTableName(table)
+	if table == "" {
+		return errEmptyTableName
+	}
+	state, err := newEncryptionState(config)
+	if err != nil {
+		return err
+	}
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		if alias.encryptions == nil {
+			alias.encryptions = make(map[string]*encryptionState)
+		}
+		alias.encryptions[table] = state
+		alias.mu.Unlock()
+	})
+}
+
+// RemoveEncryption 关闭指定连接上表的字段级加密，已写入的密文不会被解密回写。
+func RemoveEncryption(name, table string) error {
+	table = baseTableName(table)
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		delete(alias.encryptions, table)
+		alias.mu.Unlock()
+	})
+}
+
+// encryptionState 保存校验后的加密配置和按密钥 ID 初始化好的 AEAD。
+type encryptionState struct {
+	current string
+	ids     []string
+	aeads   map[string]cipher.AEAD
+	keys    map[string][]byte
+	columns map[string]EncryptedColumn
+}
+
+// newEncryptionState 校验加密配置并初始化 AES-GCM。
+func newEncryptionState(config EncryptionConfig) (*encryptionState, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("the encryption keys cannot be empty")
+	}
+	if _, ok := config.Keys[config.CurrentKey]; !ok {
+		return nil, fmt.Errorf("the current encryption key %q does not exist", config.CurrentKey)
+	}
+	if len(config.Columns) == 0 {
+		return nil, errors.New("the encrypted columns cannot be empty")
+	}
+	state := &encryptionState{
+		current: config.CurrentKey,
+		aeads:   make(map[string]cipher.AEAD, len(config.Keys)),
+		keys:    make(map[string][]byte, len(config.Keys)),
+		columns: make(map[string]EncryptedColumn, len(config.Columns)),
+	}
+	for id, key := range config.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		state.ids = append(state.ids, id)
+		state.aeads[id] = aead
+		state.keys[id] = append([]byte(nil), key...)
+	}
+	sort.Strings(state.ids)
+	for column, conf := range config.Columns {
+		column = ToField(column)
+		if column == "" {
+			return nil, errors.New("the encrypted column name cannot be empty")
+		}
+		conf.BlindIndex = ToField(conf.BlindIndex)
+		state.columns[column] = conf
+	}
+	return state, nil
+}
+
+// encrypt 使用当前密钥加密明文；deterministic 为 true 时 nonce 由明文的 HMAC 派生。
+func (s *encryptionState) encrypt(plain string, deterministic bool) (string, error) {
+	return s.encryptWith(s.current, plain, deterministic)
+}
+
+// encryptWith 使用指定密钥加密明文。
+func (s *encryptionState) encryptWith(id, plain string, deterministic bool) (string, error) {
+	aead := s.aeads[id]
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		copy(nonce, s.mac(id, "nonce", plain))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(id))
+	return encryptPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt 解密带密文前缀的值；不带前缀的值按明文原样返回。
+func (s *encryptionState) decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptPrefix)
+	if !ok {
+		return value, nil
+	}
+	id, data, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("invalid encrypted value")
+	}
+	aead, ok := s.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("the encryption key %q does not exist", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("invalid encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("decrypt value with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// blindIndex 返回明文在指定密钥下的盲索引。
+func (s *encryptionState) blindIndex(id, plain string) string {
+	return id + ":" + hex.EncodeToString(s.mac(id, "index", plain))
+}
+
+// mac 使用从密钥派生的子密钥计算 HMAC-SHA256，label 用于区分 nonce 和盲索引用途。
+func (s *encryptionState) mac(id, label, plain string) []byte {
+	sub := hmac.New(sha256.New, s.keys[id])
+	sub.Write([]byte(label))
+	h := hmac.New(sha256.New, sub.Sum(nil))
+	h.Write([]byte(plain))
+	return h.Sum(nil)
+}
+
+// encryptionConfig 返回当前 Builder 表的加密配置，未开启时返回 nil。
+func (m *Builder) encryptionConfig() *encryptionState {
+	alias, ok := lookupDataBase(m.name)
+	if !ok || alias == nil {
+		return nil
+	}
+	table := baseTableName(m.table)
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	return alias.encryptions[table]
+}
+
+// encryptDatas 返回加密字段已替换为密文、并补齐盲索引字段的写入数据，不会修改传入的 data。
+func (m *Builder) encryptDatas(data Datas) (Datas, error) {
+	state := m.encryptionConfig()
+	if state == nil {
+		return data, nil
+	}
+	out := make(Datas, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	for k, v := range data {
+		conf, ok := state.columns[ToField(k)]
+		if !ok {
+			continue
+		}
+		if v == nil {
+			if conf.BlindIndex != "" {
+				out[conf.BlindIndex] = nil
+			}
+			continue
+		}
+		if isRawExpr(v) {
+			return nil, fmt.Errorf("the encrypted column %s cannot be written with Raw", ToField(k))
+		}
+		plain := encryptPlainText(v)
+		cipherText, err := state.encrypt(plain, conf.Deterministic)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = cipherText
+		if conf.BlindIndex != "" {
+			out[conf.BlindIndex] = state.blindIndex(state.current, plain)
+		}
+	}
+	return out, nil
+}
+
+// checkEncryptedRaw 在原始 SQL 片段中出现加密字段名时返回错误，method 为调用的写入方法名。
+//
+// 原始片段无法可靠地加密，因此按标识符粗略匹配，字符串字面量中出现字段名也会被拒绝。
+func (m *Builder) checkEncryptedRaw(method, sqlraw string) error {
+	state := m.encryptionConfig()
+	if state == nil {
+		return nil
+	}
+	words := strings.FieldsFunc(sqlraw, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		if _, ok := state.columns[word]; ok {
+			return fmt.Errorf("the encrypted column %s cannot be written with %s", word, method)
+		}
+	}
+	return nil
+}
+
+// decryptParams 原地解密查询结果中加密字段带密文前缀的值，其它字段即使以密文前缀开头也保持原样。
+func (m *Builder) decryptParams(list []Params) error {
+	state := m.encryptionConfig()
+	if state == nil {
+		return nil
+	}
+	for _, item := range list {
+		for k, v := range item {
+			if !strings.HasPrefix(v, encryptPrefix) {
+				continue
+			}
+			if _, ok := state.columns[normalizeResultField(k)]; !ok {
+				continue
+			}
+			plain, err := state.decrypt(v)
+			if err != nil {
+				return fmt.Errorf("decrypt %s: %w", k, err)
+			}
+			item[k] = plain
+		}
+	}
+	return nil
+}
+
+// encryptedWhere 将加密字段上的等值和 in 条件改写为密文或盲索引集合匹配。
+//
+// ok 为 false 表示条件不涉及可查询的加密字段，调用方应按普通条件处理。
+func (m *Builder) encryptedWhere(a []string) (where string, args []any, ok bool) {
+	if len(a) < 2 || len(a) > 3 || strings.Contains(a[0], "|") {
+		return "", nil, false
+	}
+	if len(a) == 2 {
+		return m.encryptedCondition(a[0], "=", []any{a[1]})
+	}
+	operator := strings.ToLower(strings.TrimSpace(a[1]))
+	switch operator {
+	case "=", "!=", "<>":
+		return m.encryptedCondition(a[0], operator, []any{a[2]})
+	case "in", "not in":
+		values := strings.Split(a[2], ",")
+		list := make([]any, len(values))
+		for i, v := range values {
+			list[i] = strings.TrimSpace(v)
+		}
+		return m.encryptedCondition(a[0], operator, list)
+	}
+	return "", nil, false
+}
+
+// encryptedCondition 按全部密钥计算 values 的密文或盲索引，生成 in 或 not in 条件。
+func (m *Builder) encryptedCondition(field, operator string, values []any) (string, []any, bool) {
+	state := m.encryptionConfig()
+	if state == nil || len(values) == 0 {
+		return "", nil, false
+	}
+	conf, ok := state.columns[normalizeResultField(field)]
+	if !ok || (!conf.Deterministic && conf.BlindIndex == "") {
+		return "", nil, false
+	}
+	target := field
+	if conf.BlindIndex != "" {
+		target = conf.BlindIndex
+		if index := strings.LastIndex(field, "."); index >= 0 {
+			target = field[:index+1] + conf.BlindIndex
+		}
+	}
+	args := make([]any, 0, len(values)*len(state.ids))
+	for _, v := range values {
+		plain := encryptPlainText(v)
+		for _, id := range state.ids {
+			if conf.BlindIndex != "" {
+				args = append(args, state.blindIndex(id, plain))
+				continue
+			}
+			cipherText, err := state.encryptWith(id, plain, true)
+			if err != nil {
+				return "", nil, false
+			}
+			args = append(args, cipherText)
+		}
+	}
+	seats := make([]string, len(args))
+	for i := range seats {
+		seats[i] = paramSeat
+	}
+	if operator == "=" || operator == "in" {
+		operator = "in"
+	} else {
+		operator = "not in"
+	}
+	return target + " " + operator + "(" + strings.Join(seats, ",") + ")", args, true
+}
+
+// encryptPlainText 将写入值或查询值转换为参与加密的明文。
+func encryptPlainText(v any) string {
+	switch d := v.(type) {
+	case string:
+		return d
+	case []byte:
+		return string(d)
+	case time.Time:
+		return d.Format(time.DateTime)
+	}
+	return fmt.Sprint(v)
+}
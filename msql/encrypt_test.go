@@ -0,0 +1,210 @@
+package msql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var (
+	testKeyOld = bytes.Repeat([]byte("o"), 32)
+	testKeyNew = bytes.Repeat([]byte("n"), 16)
+)
+
+func newTestEncryptionState(t *testing.T, current string, keys map[string][]byte) *encryptionState {
+	t.Helper()
+	state, err := newEncryptionState(EncryptionConfig{
+		Keys:       keys,
+		CurrentKey: current,
+		Columns:    map[string]EncryptedColumn{"phone": {BlindIndex: "phone_index"}},
+	})
+	if err != nil {
+		t.Fatalf("newEncryptionState() error: %v", err)
+	}
+	return state
+}
+
+func TestNewEncryptionStateErrors(t *testing.T) {
+	columns := map[string]EncryptedColumn{"phone": {}}
+	tests := []struct {
+		name   string
+		config EncryptionConfig
+		want   string
+	}{
+		{"no keys", EncryptionConfig{Columns: columns}, "the encryption keys cannot be empty"},
+		{
+			"missing current",
+			EncryptionConfig{Keys: map[string][]byte{"a": testKeyOld}, CurrentKey: "b", Columns: columns},
+			`the current encryption key "b" does not exist`,
+		},
+		{
+			"no columns",
+			EncryptionConfig{Keys: map[string][]byte{"a": testKeyOld}, CurrentKey: "a"},
+			"the encrypted columns cannot be empty",
+		},
+		{
+			"colon in id",
+			EncryptionConfig{Keys: map[string][]byte{"a:1": testKeyOld}, CurrentKey: "a:1", Columns: columns},
+			`invalid encryption key id "a:1"`,
+		},
+		{
+			"bad key size",
+			EncryptionConfig{Keys: map[string][]byte{"a": []byte("short")}, CurrentKey: "a", Columns: columns},
+			`encryption key "a": crypto/aes: invalid key size 5`,
+		},
+	}
+	for _, tt := range tests {
+		_, err := newEncryptionState(tt.config)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: newEncryptionState() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	state := newTestEncryptionState(t, "2025", map[string][]byte{"2025": testKeyNew})
+	tests := []struct {
+		plain         string
+		deterministic bool
+	}{
+		{"13800000000", false},
+		{"13800000000", true},
+		{"", false},
+		{"中文:with:colons", true},
+	}
+	for _, tt := range tests {
+		first, err := state.encrypt(tt.plain, tt.deterministic)
+		if err != nil {
+			t.Fatalf("encrypt(%q) error: %v", tt.plain, err)
+		}
+		if !strings.HasPrefix(first, encryptPrefix+"2025:") {
+			t.Errorf("encrypt(%q) = %q, want prefix %q", tt.plain, first, encryptPrefix+"2025:")
+		}
+		second, _ := state.encrypt(tt.plain, tt.deterministic)
+		if (first == second) != tt.deterministic {
+			t.Errorf("encrypt(%q, %v) twice equal = %v", tt.plain, tt.deterministic, first == second)
+		}
+		plain, err := state.decrypt(first)
+		if err != nil || plain != tt.plain {
+			t.Errorf("decrypt(encrypt(%q)) = %q, %v", tt.plain, plain, err)
+		}
+	}
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	before := newTestEncryptionState(t, "2024", map[string][]byte{"2024": testKeyOld})
+	oldCipher, err := before.encrypt("13800000000", false)
+	if err != nil {
+		t.Fatalf("encrypt() error: %v", err)
+	}
+	rotated := newTestEncryptionState(t, "2025", map[string][]byte{"2024": testKeyOld, "2025": testKeyNew})
+	if plain, err := rotated.decrypt(oldCipher); err != nil || plain != "13800000000" {
+		t.Errorf("decrypt old value after rotation = %q, %v", plain, err)
+	}
+	newCipher, _ := rotated.encrypt("13800000000", false)
+	if !strings.HasPrefix(newCipher, encryptPrefix+"2025:") {
+		t.Errorf("encrypt() after rotation = %q, want the new key", newCipher)
+	}
+	if got, want := before.blindIndex("2024", "x"), rotated.blindIndex("2024", "x"); got != want {
+		t.Errorf("blind index changed after rotation: %q != %q", got, want)
+	}
+	retired := newTestEncryptionState(t, "2025", map[string][]byte{"2025": testKeyNew})
+	if _, err := retired.decrypt(oldCipher); err == nil || !strings.Contains(err.Error(), `"2024" does not exist`) {
+		t.Errorf("decrypt with retired key error = %v", err)
+	}
+}
+
+func TestDecryptInvalidValues(t *testing.T) {
+	state := newTestEncryptionState(t, "k", map[string][]byte{"k": testKeyNew})
+	valid, _ := state.encrypt("secret", false)
+	tampered := valid[:len(valid)-4] + "AAAA"
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain text", "plain text", false},
+		{encryptPrefix + "missing-separator", "", true},
+		{encryptPrefix + "k:not base64!", "", true},
+		{encryptPrefix + "k:AAAA", "", true},
+		{tampered, "", true},
+	}
+	for _, tt := range tests {
+		got, err := state.decrypt(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("decrypt(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEncryptionBuilder(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	err := SetEncryption(name, "users", EncryptionConfig{
+		Keys:       map[string][]byte{"2024": testKeyOld, "2025": testKeyNew},
+		CurrentKey: "2025",
+		Columns: map[string]EncryptedColumn{
+			"phone":  {BlindIndex: "phone_index"},
+			"secret": {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetEncryption() error: %v", err)
+	}
+	state := Model("users", name).encryptionConfig()
+
+	if _, err := Model("users", name).Insert(Datas{"phone": "138", "secret": "s", "name": "tom"}); err != nil {
+		t.Fatalf("Insert() error: %v", err)
+	}
+	q, _ := fake.LastQuery()
+	var encrypted, indexes int
+	for _, arg := range q.Args {
+		s, _ := arg.(string)
+		switch {
+		case strings.HasPrefix(s, encryptPrefix+"2025:"):
+			encrypted++
+		case s == state.blindIndex("2025", "138"):
+			indexes++
+		case s == "138" || s == "s":
+			t.Errorf("Insert() sent plain text %q", s)
+		}
+	}
+	if encrypted != 2 || indexes != 1 {
+		t.Errorf("Insert() sent %d ciphertexts and %d blind indexes, want 2 and 1", encrypted, indexes)
+	}
+
+	oldPhone, _ := state.encryptWith("2024", "138", false)
+	secret, _ := state.encrypt("s", false)
+	fake.Expect(`^select`).WillReturnRows(
+		[]string{"phone", "secret", "note", "alias_phone"},
+		[]any{oldPhone, secret, oldPhone, oldPhone},
+	)
+	row, err := Model("users", name).Where("phone", "=", "138").Find()
+	if err != nil {
+		t.Fatalf("Find() error: %v", err)
+	}
+	q, _ = fake.LastQuery()
+	if want := "select * from users where phone_index in(?,?) limit 1"; q.Query != want {
+		t.Errorf("query = %q, want %q", q.Query, want)
+	}
+	if len(q.Args) != 2 || q.Args[0] != state.blindIndex("2024", "138") || q.Args[1] != state.blindIndex("2025", "138") {
+		t.Errorf("args = %v, want blind indexes for both keys", q.Args)
+	}
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"phone", "138"},
+		{"secret", "s"},
+		{"note", oldPhone},
+		{"alias_phone", oldPhone},
+	}
+	for _, tt := range tests {
+		if row[tt.field] != tt.want {
+			t.Errorf("Find() %s = %q, want %q", tt.field, row[tt.field], tt.want)
+		}
+	}
+
+	if _, err := Model("users", name).Where("id", "=", "1").Update(Datas{"secret": Raw("upper(name)")}); err == nil {
+		t.Error("Update() with Raw on an encrypted column returned no error")
+	}
+}
@@ -0,0 +1,336 @@
+package msql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExplainPlan 表示规范化后的执行计划。
+//
+// MySQL 来自 EXPLAIN FORMAT=JSON，PostgreSQL 来自 EXPLAIN (FORMAT JSON)；
+// 两种格式都会整理为按出现顺序排列的表访问节点。
+type ExplainPlan struct {
+	// Driver 为生成执行计划的数据库驱动名。
+	Driver string
+	// Query 为被 explain 的可执行 SQL。
+	Query string
+	// Cost 为优化器估算的总成本。
+	Cost float64
+	// Rows 为优化器估算的扫描行数；MySQL 为各表 rows_examined_per_scan 之和，PostgreSQL 为根节点 Plan Rows。
+	Rows int64
+	// FullScan 表示任一节点存在全表扫描。
+	FullScan bool
+	// Nodes 为执行计划中的表访问节点。
+	Nodes []ExplainNode
+	// Raw 为数据库返回的原始 JSON 执行计划。
+	Raw string
+}
+
+// ExplainNode 表示执行计划中的一个表访问节点。
+type ExplainNode struct {
+	// Table 为访问的表名或表别名。
+	Table string
+	// Access 为访问方式，MySQL 为 access_type，PostgreSQL 为 Node Type。
+	Access string
+	// Index 为使用的索引名，未使用索引时为空。
+	Index string
+	// Rows 为该节点估算的扫描行数。
+	Rows int64
+	// Cost 为该节点估算的成本。
+	Cost float64
+	// FullScan 表示该节点为全表扫描，即 MySQL access_type=ALL 或 PostgreSQL Seq Scan。
+	FullScan bool
+}
+
+// SlowQuery 表示一次超过慢查询阈值的查询及其自动 explain 结果。
+type SlowQuery struct {
+	// Alias 为执行查询的数据库别名。
+	Alias string
+	// Query 为实际执行的 SQL。
+	Query string
+	// Args 为实际执行的绑定参数。
+	Args []any
+	// Duration 为查询和读取结果的总耗时。
+	Duration time.Duration
+	// Time 为查询开始时间。
+	Time time.Time
+	// Plan 为自动 explain 得到的执行计划，explain 失败时为 nil。
+	Plan *ExplainPlan
+	// ExplainErr 为自动 explain 失败时的错误。
+	ExplainErr error
+}
+
+// Explain 使用 explain 分析当前 Builder 构造出的 select SQL，并返回规范化执行计划。
+//
+// Explain 不会重置 Builder 的查询条件，分析后仍可继续调用 Select、Find 等执行方法。
+// 当前 Builder 已开启事务时，explain 会在同一事务中执行。
+//
+// 示例：
+//
+//	plan, err := msql.Model("users").Where("status", "=", "enabled").Explain()
+//	if err == nil && plan.FullScan { ... }
+func (m *Builder) Explain() (*ExplainPlan, error) {
+	rawQuery, err := m.buildSql()
+	if err != nil {
+		return nil, err
+	}
+	query := renderParamSeats(m.name, rawQuery, 0)
+	args := m.getQueryArgs(true)
+	m.lastsql = renderDebugParamSeats(explainPrefix(m.name)+rawQuery, args)
+	return explainQuery(m.context(), m.name, query, m.tx, args)
+}
+
+// explainQuery 对可执行 SQL 执行 explain，并解析返回的 JSON 执行计划。
+func explainQuery(ctx context.Context, name, query string, tx *sql.Tx, args []any) (*ExplainPlan, error) {
+	vs, err := queryRawValues(ctx, name, explainPrefix(name)+query, tx, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(vs) < 1 || len(vs[0]) != 1 {
+		return nil, errors.New("explain returned an unexpected result")
+	}
+	var raw string
+	for _, v := range vs[0] {
+		raw = v
+	}
+	driver := DriverMysql
+	if isPostgres(name) {
+		driver = DriverPostgres
+	}
+	plan, err := parseExplainPlan(driver, raw)
+	if err != nil {
+		return nil, err
+	}
+	plan.Query = query
+	return plan, nil
+}
+
+// explainPrefix 返回当前驱动输出 JSON 执行计划的 explain 前缀。
+func explainPrefix(name string) string {
+	if isPostgres(name) {
+		return "EXPLAIN (FORMAT JSON) "
+	}
+	return "EXPLAIN FORMAT=JSON "
+}
+
+// parseExplainPlan 将驱动返回的 JSON 执行计划整理为 ExplainPlan。
+func parseExplainPlan(driver, raw string) (*ExplainPlan, error) {
+	var doc any
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, fmt.Errorf("parse explain plan: %w", err)
+	}
+	plan := &ExplainPlan{Driver: driver, Raw: raw}
+	if driver == DriverPostgres {
+		list, ok := doc.([]any)
+		if !ok || len(list) == 0 {
+			return nil, errors.New("parse explain plan: unexpected postgres format")
+		}
+		item, _ := list[0].(map[string]any)
+		root, ok := item["Plan"].(map[string]any)
+		if !ok {
+			return nil, errors.New("parse explain plan: missing postgres plan")
+		}
+		plan.Cost = explainFloat(root["Total Cost"])
+		plan.Rows = int64(explainFloat(root["Plan Rows"]))
+		collectPostgresExplainNodes(plan, root)
+		return plan, nil
+	}
+	root, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("parse explain plan: unexpected mysql format")
+	}
+	if block, ok := root["query_block"].(map[string]any); ok {
+		if costInfo, ok := block["cost_info"].(map[string]any); ok {
+			plan.Cost = explainFloat(costInfo["query_cost"])
+		}
+	}
+	collectMysqlExplainNodes(plan, root)
+	return plan, nil
+}
+
+// collectMysqlExplainNodes 递归收集 MySQL JSON 执行计划中的 table 节点。
+//
+// MySQL 会把表访问节点嵌套在 nested_loop、ordering_operation、grouping_operation 等结构中，
+// 这里按 JSON 键名排序遍历，保证同一计划得到稳定的节点顺序。
+func collectMysqlExplainNodes(plan *ExplainPlan, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if table, ok := v["table"].(map[string]any); ok {
+			node := ExplainNode{
+				Table:  explainString(table["table_name"]),
+				Access: explainString(table["access_type"]),
+				Index:  explainString(table["key"]),
+				Rows:   int64(explainFloat(table["rows_examined_per_scan"])),
+			}
+			if costInfo, ok := table["cost_info"].(map[string]any); ok {
+				node.Cost = explainFloat(costInfo["prefix_cost"])
+			}
+			node.FullScan = strings.EqualFold(node.Access, "ALL")
+			plan.addNode(node)
+		}
+		for _, key := range sortedExplainKeys(v) {
+			collectMysqlExplainNodes(plan, v[key])
+		}
+	case []any:
+		for _, item := range v {
+			collectMysqlExplainNodes(plan, item)
+		}
+	}
+}
+
+// collectPostgresExplainNodes 递归收集 PostgreSQL JSON 执行计划中访问表的节点。
+func collectPostgresExplainNodes(plan *ExplainPlan, node map[string]any) {
+	if relation := explainString(node["Relation Name"]); relation != "" {
+		access := explainString(node["Node Type"])
+		table := relation
+		if alias := explainString(node["Alias"]); alias != "" && alias != relation {
+			table = relation + " " + alias
+		}
+		plan.addNode(ExplainNode{
+			Table:    table,
+			Access:   access,
+			Index:    explainString(node["Index Name"]),
+			Rows:     int64(explainFloat(node["Plan Rows"])),
+			Cost:     explainFloat(node["Total Cost"]),
+			FullScan: access == "Seq Scan",
+		})
+	}
+	children, _ := node["Plans"].([]any)
+	for _, child := range children {
+		if sub, ok := child.(map[string]any); ok {
+			collectPostgresExplainNodes(plan, sub)
+		}
+	}
+}
+
+// addNode 追加表访问节点，并同步更新全表扫描标记和 MySQL 估算行数。
+func (p *ExplainPlan) addNode(node ExplainNode) {
+	p.Nodes = append(p.Nodes, node)
+	if node.FullScan {
+		p.FullScan = true
+	}
+	if p.Driver != DriverPostgres {
+		p.Rows += node.Rows
+	}
+}
+
+// sortedExplainKeys 返回 JSON 对象按字典序排列的键。
+func sortedExplainKeys(v map[string]any) []string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// explainString 将 JSON 值转换为字符串，非字符串值返回空字符串。
+func explainString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// explainFloat 将 JSON 数字或数字字符串转换为 float64。
+//
+// MySQL 的 cost_info 使用字符串表示数值，PostgreSQL 使用 JSON 数字。
+func explainFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+var (
+	// ErrExplainInTx 表示慢查询发生在事务内，未执行自动 explain。
+	ErrExplainInTx = errors.New("explain skipped inside a transaction")
+	// ErrExplainBusy 表示同时进行的自动 explain 过多，本次慢查询未执行 explain。
+	ErrExplainBusy = errors.New("explain skipped because too many explains are running")
+)
+
+// explainSlots 限制同时进行的自动 explain 数量，避免慢查询集中出现时占满连接池。
+var explainSlots = make(chan struct{}, 4)
+
+// explainSlowQuery 在查询耗时达到别名慢查询阈值时执行自动 explain。
+//
+// 仅 select 和 with 开头的查询会被分析；explain 本身不会再次触发自动 explain。
+// explain 和 handler 在独立 goroutine 中执行，不会增加调用方的查询耗时。
+// 事务内的慢查询不执行 explain，ExplainErr 为 ErrExplainInTx，避免监控影响调用方事务；
+// 同时进行的 explain 超过上限时 ExplainErr 为 ErrExplainBusy。
+func explainSlowQuery(name, query string, tx *sql.Tx, args []any, start time.Time) {
+	alias, ok := lookupDataBase(name)
+	if !ok || alias == nil {
+		return
+	}
+	threshold, handler := aliasSlowQuery(alias)
+	if threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < threshold || !isExplainableQuery(query) {
+		return
+	}
+	slow := SlowQuery{
+		Alias:    alias.name,
+		Query:    query,
+		Args:     append([]any(nil), args...),
+		Duration: elapsed,
+		Time:     start,
+	}
+	explain := false
+	if tx != nil {
+		// explain 失败会让 PostgreSQL 事务进入中止状态，且在连接池上执行可能因等待连接阻塞，
+		// 因此事务内的慢查询只记录不分析。
+		slow.ExplainErr = ErrExplainInTx
+	} else {
+		select {
+		case explainSlots <- struct{}{}:
+			explain = true
+		default:
+			slow.ExplainErr = ErrExplainBusy
+		}
+	}
+	go func() {
+		if explain {
+			slow.Plan, slow.ExplainErr = explainQuery(context.Background(), name, query, nil, slow.Args)
+			<-explainSlots
+		}
+		if handler != nil {
+			handler(slow)
+			return
+		}
+		fmt.Println(formatSlowQueryLog(slow))
+	}()
+}
+
+// isExplainableQuery 判断 SQL 是否为可自动 explain 的查询语句。
+func isExplainableQuery(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	return strings.EqualFold(fields[0], "select") || strings.EqualFold(fields[0], "with")
+}
+
+// formatSlowQueryLog 生成单行慢查询日志，格式与 SQL 调试日志保持一致。
+func formatSlowQueryLog(slow SlowQuery) string {
+	log := "[slow-sql][" + slow.Alias + "][" + slow.Time.Format("2006-01-02 15:04:05.000") + "]" +
+		"[duration=" + slow.Duration.String() + "]"
+	switch {
+	case slow.Plan != nil:
+		log += "[full_scan=" + formatSQLLogTx(slow.Plan.FullScan) + "][rows=" + strconv.FormatInt(slow.Plan.Rows, 10) + "]"
+	case slow.ExplainErr != nil:
+		log += "[explain_error=" + quoteSQLLogString(slow.ExplainErr.Error(), sqlLogQueryMaxRunes) + "]"
+	}
+	return log + "[query=" + quoteSQLLogString(slow.Query, sqlLogQueryMaxRunes) + "][args=" + formatSQLLogArgs(slow.Args) + "]"
+}
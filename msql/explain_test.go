@@ -0,0 +1,133 @@
+package msql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testMysqlPlan = `{"query_block":{"cost_info":{"query_cost":"12.50"},"nested_loop":[
+		{"table":{"table_name":"u","access_type":"ALL","rows_examined_per_scan":100,"cost_info":{"prefix_cost":"10.00"}}},
+		{"table":{"table_name":"o","access_type":"ref","key":"idx_user","rows_examined_per_scan":3,"cost_info":{"prefix_cost":"12.50"}}}]}}`
+	testPostgresPlan = `[{"Plan":{"Node Type":"Hash Join","Total Cost":42.5,"Plan Rows":7,"Plans":[
+		{"Node Type":"Seq Scan","Relation Name":"users","Alias":"u","Total Cost":20,"Plan Rows":50},
+		{"Node Type":"Index Scan","Relation Name":"orders","Alias":"orders","Index Name":"orders_pkey","Total Cost":8.3,"Plan Rows":1}]}}]`
+)
+
+func TestParseExplainPlan(t *testing.T) {
+	tests := []struct {
+		driver   string
+		raw      string
+		cost     float64
+		rows     int64
+		fullScan bool
+		nodes    []ExplainNode
+		wantErr  bool
+	}{
+		{
+			DriverMysql, testMysqlPlan, 12.5, 103, true,
+			[]ExplainNode{
+				{Table: "u", Access: "ALL", Rows: 100, Cost: 10, FullScan: true},
+				{Table: "o", Access: "ref", Index: "idx_user", Rows: 3, Cost: 12.5},
+			},
+			false,
+		},
+		{
+			DriverPostgres, testPostgresPlan, 42.5, 7, true,
+			[]ExplainNode{
+				{Table: "users u", Access: "Seq Scan", Rows: 50, Cost: 20, FullScan: true},
+				{Table: "orders", Access: "Index Scan", Index: "orders_pkey", Rows: 1, Cost: 8.3},
+			},
+			false,
+		},
+		{DriverMysql, `not json`, 0, 0, false, nil, true},
+		{DriverMysql, `[]`, 0, 0, false, nil, true},
+		{DriverPostgres, `[]`, 0, 0, false, nil, true},
+		{DriverPostgres, `[{"Planning":{}}]`, 0, 0, false, nil, true},
+	}
+	for _, tt := range tests {
+		plan, err := parseExplainPlan(tt.driver, tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseExplainPlan(%s, %.20q) returned no error", tt.driver, tt.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("parseExplainPlan(%s) error: %v", tt.driver, err)
+		}
+		if plan.Cost != tt.cost || plan.Rows != tt.rows || plan.FullScan != tt.fullScan {
+			t.Errorf("%s plan cost=%v rows=%d full=%v, want %v %d %v",
+				tt.driver, plan.Cost, plan.Rows, plan.FullScan, tt.cost, tt.rows, tt.fullScan)
+		}
+		if !reflect.DeepEqual(plan.Nodes, tt.nodes) {
+			t.Errorf("%s nodes = %+v, want %+v", tt.driver, plan.Nodes, tt.nodes)
+		}
+	}
+}
+
+func TestBuilderExplain(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverPostgres)
+	fake.Expect(`^EXPLAIN \(FORMAT JSON\) `).WillReturnRows([]string{"QUERY PLAN"}, []any{testPostgresPlan})
+	m := Model("users u", name).Where("u.status", "=", "on")
+	plan, err := m.Explain()
+	if err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+	if want := "select * from users u where u.status=$1"; plan.Query != want || plan.Driver != DriverPostgres {
+		t.Errorf("plan query = %q driver = %q, want %q postgres", plan.Query, plan.Driver, want)
+	}
+	if q, _ := fake.LastQuery(); !reflect.DeepEqual(q.Args, []any{"on"}) {
+		t.Errorf("explain args = %v, want [on]", q.Args)
+	}
+	if _, err := m.Select(); err != nil {
+		t.Fatalf("Select() after Explain() error: %v", err)
+	}
+	if q, _ := fake.LastQuery(); q.Query != "select * from users u where u.status=$1" {
+		t.Errorf("Select() after Explain() ran %q, want the same conditions", q.Query)
+	}
+}
+
+func TestSlowQueryExplain(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	fake.Expect(`^EXPLAIN FORMAT=JSON `).WillReturnRows([]string{"EXPLAIN"}, []any{testMysqlPlan})
+	slow := make(chan SlowQuery, 2)
+	if err := SetSlowQueryExplain(name, time.Nanosecond, func(q SlowQuery) { slow <- q }); err != nil {
+		t.Fatalf("SetSlowQueryExplain() error: %v", err)
+	}
+	receive := func() SlowQuery {
+		t.Helper()
+		select {
+		case q := <-slow:
+			return q
+		case <-time.After(time.Second):
+			t.Fatal("slow query handler was not called")
+		}
+		return SlowQuery{}
+	}
+
+	if _, err := Model("users", name).Where("id", "=", "1").Select(); err != nil {
+		t.Fatalf("Select() error: %v", err)
+	}
+	q := receive()
+	if q.ExplainErr != nil || q.Plan == nil || !q.Plan.FullScan {
+		t.Errorf("slow query = %+v, want a full scan plan", q)
+	}
+	if q.Query != "select * from users where id=?" || !reflect.DeepEqual(q.Args, []any{"1"}) {
+		t.Errorf("slow query = %q %v", q.Query, q.Args)
+	}
+
+	m := Model("users", name)
+	if err := m.Begin(); err != nil {
+		t.Fatalf("Begin() error: %v", err)
+	}
+	defer func() { _ = m.Rollback() }()
+	if _, err := m.Where("id", "=", "2").Select(); err != nil {
+		t.Fatalf("Select() in a transaction error: %v", err)
+	}
+	if q := receive(); !errors.Is(q.ExplainErr, ErrExplainInTx) || q.Plan != nil {
+		t.Errorf("slow query in a transaction = %+v, want ErrExplainInTx", q)
+	}
+}
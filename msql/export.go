@@ -0,0 +1,188 @@
+package msql
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excelSheetMaxRows 为 xlsx 单个工作表允许的最大行数，包含表头行。
+const excelSheetMaxRows = 1048576
+
+// ExportFields 为导出的列定义，Field 为结果字段名，Header 为表头。
+//
+// 底层类型与 tool.Fields 相同，已有的 tool.Fields 可通过 msql.ExportFields(fields) 直接转换。
+type ExportFields []struct {
+	Field  string
+	Header string
+}
+
+// ExportCSV 按当前查询条件逐行读取结果并写入 CSV，返回写入的数据行数（不含表头）。
+//
+// fields 的 Field 为结果字段名，Header 为表头；fields 为空时按查询结果字段顺序
+// 使用字段名作为表头。结果不会整体加载到内存，加密字段会先解密再写入。
+// 需要在 Excel 中直接打开时，调用方可先向 w 写入 UTF-8 BOM。ExportCSV 执行后会调用 Reset。
+//
+// 示例：
+//
+//	f, _ := os.Create("orders.csv")
+//	defer f.Close()
+//	n, err := msql.Model("orders").Where("status", "=", "paid").
+//	    ExportCSV(f, msql.ExportFields{{Field: "id", Header: "订单ID"}, {Field: "amount", Header: "金额"}})
+func (m *Builder) ExportCSV(w io.Writer, fields ExportFields) (int64, error) {
+	writer := csv.NewWriter(w)
+	var total int64
+	err := m.eachRow(fields, func(header []string) error {
+		if len(header) == 0 {
+			return nil
+		}
+		return writer.Write(header)
+	}, func(record []string) error {
+		total++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return total, err
+	}
+	writer.Flush()
+	return total, writer.Error()
+}
+
+// ExportExcel 按当前查询条件逐行读取结果并使用 excelize 流式写入器生成 xlsx 写入 w，返回写入的数据行数。
+//
+// title 为工作表名称，为空时使用 "Excel数据导出"；数据超过单个工作表行数上限时会自动续写到
+// title_2、title_3 等工作表，每个工作表都带表头。fields 规则与 ExportCSV 一致，单元格统一按字符串写入。
+// ExportExcel 执行后会调用 Reset。
+//
+// 示例：
+//
+//	w.Header().Set("Content-Disposition", `attachment; filename="orders.xlsx"`)
+//	n, err := msql.Model("orders").Order("id").ExportExcel(w, fields, "订单")
+func (m *Builder) ExportExcel(w io.Writer, fields ExportFields, title string) (int64, error) {
+	if title == "" {
+		title = "Excel数据导出"
+	}
+	f := excelize.NewFile()
+	defer func(f *excelize.File) {
+		_ = f.Close()
+	}(f)
+	if err := f.SetSheetName(f.GetSheetName(f.GetActiveSheetIndex()), title); err != nil {
+		return 0, err
+	}
+	var (
+		total  int64
+		sheet  int
+		row    int
+		header []any
+		stream *excelize.StreamWriter
+	)
+	nextSheet := func() error {
+		if stream != nil {
+			if err := stream.Flush(); err != nil {
+				return err
+			}
+		}
+		sheet++
+		name := title
+		if sheet > 1 {
+			name = title + "_" + strconv.Itoa(sheet)
+			if _, err := f.NewSheet(name); err != nil {
+				return err
+			}
+		}
+		var err error
+		if stream, err = f.NewStreamWriter(name); err != nil {
+			return err
+		}
+		if err := stream.SetColWidth(1, max(len(header), 1), 24); err != nil {
+			return err
+		}
+		row = 1
+		return stream.SetRow("A1", header)
+	}
+	err := m.eachRow(fields, func(names []string) error {
+		header = make([]any, len(names))
+		for i, name := range names {
+			header[i] = name
+		}
+		return nextSheet()
+	}, func(record []string) error {
+		if row >= excelSheetMaxRows {
+			if err := nextSheet(); err != nil {
+				return err
+			}
+		}
+		row++
+		total++
+		cells := make([]any, len(record))
+		for i, v := range record {
+			cells[i] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return stream.SetRow(cell, cells)
+	})
+	if err != nil {
+		return total, err
+	}
+	if stream == nil {
+		return total, nil
+	}
+	if err := stream.Flush(); err != nil {
+		return total, err
+	}
+	return total, f.Write(w)
+}
+
+// eachRow 执行当前查询并逐行回调，供导出方法在不缓存全部结果的情况下读取数据。
+//
+// header 在读取首行前调用一次，参数为表头；record 按表头顺序接收每行字段值。
+// fields 为空时使用查询结果字段顺序；此时如果查询结果为空，header 会收到空表头。
+func (m *Builder) eachRow(fields ExportFields, header func([]string) error, record func([]string) error) error {
+	defer m.Reset()
+	rawQuery, err := m.buildSql()
+	if err != nil {
+		return err
+	}
+	query := renderParamSeats(m.name, rawQuery, 0)
+	args := m.getQueryArgs(true)
+	m.lastsql = renderDebugParamSeats(rawQuery, args)
+	var keys []string
+	if len(fields) > 0 {
+		keys = make([]string, len(fields))
+		names := make([]string, len(fields))
+		for i, field := range fields {
+			keys[i], names[i] = field.Field, field.Header
+		}
+		if err := header(names); err != nil {
+			return err
+		}
+	}
+	err = eachRawRow(m.context(), m.name, query, m.tx, args, func(cols []string, item Params) error {
+		if keys == nil {
+			keys = cols
+			if err := header(cols); err != nil {
+				return err
+			}
+		}
+		if err := m.decryptParams([]Params{item}); err != nil {
+			return err
+		}
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = item[key]
+		}
+		return record(values)
+	})
+	if err != nil {
+		return err
+	}
+	if keys == nil && len(fields) == 0 {
+		return header(nil)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package msql
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportCSV(t *testing.T) {
+	columns := []string{"id", "name", "amount"}
+	tests := []struct {
+		name   string
+		fields ExportFields
+		rows   [][]any
+		want   string
+		total  int64
+	}{
+		{
+			"result columns", nil,
+			[][]any{{1, "tom", "9.9"}, {2, "a,b", "1"}},
+			"id,name,amount\n1,tom,9.9\n2,\"a,b\",1\n", 2,
+		},
+		{
+			"selected fields",
+			ExportFields{{Field: "amount", Header: "金额"}, {Field: "id", Header: "订单ID"}},
+			[][]any{{1, "tom", "9.9"}},
+			"金额,订单ID\n9.9,1\n", 1,
+		},
+		{
+			"empty result with fields",
+			ExportFields{{Field: "id", Header: "订单ID"}},
+			nil,
+			"订单ID\n", 0,
+		},
+		{"empty result without fields", nil, nil, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, DriverPostgres)
+			fake.Expect(`^select \* from orders where status=\$1$`).WillReturnRows(columns, tt.rows...)
+			var buf bytes.Buffer
+			m := Model("orders", name).Where("status", "=", "paid")
+			n, err := m.ExportCSV(&buf, tt.fields)
+			if err != nil || n != tt.total {
+				t.Fatalf("ExportCSV() = %d, %v; want %d, nil", n, err, tt.total)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("ExportCSV() wrote %q, want %q", buf.String(), tt.want)
+			}
+			if q, _ := fake.LastQuery(); len(q.Args) != 1 || q.Args[0] != "paid" {
+				t.Errorf("export args = %v, want [paid]", q.Args)
+			}
+			if len(m.where) != 0 {
+				t.Errorf("ExportCSV() kept conditions %v, want Reset", m.where)
+			}
+		})
+	}
+}
+
+func TestExportExcel(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	fake.Expect(`^select`).WillReturnRows([]string{"id", "name"}, []any{1, "tom"}, []any{2, "bob"})
+	var buf bytes.Buffer
+	n, err := Model("users", name).ExportExcel(&buf, ExportFields{{Field: "name", Header: "姓名"}}, "")
+	if err != nil || n != 2 {
+		t.Fatalf("ExportExcel() = %d, %v; want 2, nil", n, err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("open exported xlsx: %v", err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("Excel数据导出")
+	if err != nil {
+		t.Fatalf("GetRows() error: %v", err)
+	}
+	want := [][]string{{"姓名"}, {"tom"}, {"bob"}}
+	if len(rows) != len(want) {
+		t.Fatalf("sheet rows = %v, want %v", rows, want)
+	}
+	for i := range want {
+		if len(rows[i]) != 1 || rows[i][0] != want[i][0] {
+			t.Errorf("sheet row %d = %v, want %v", i, rows[i], want[i])
+		}
+	}
+}
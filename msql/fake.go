@@ -0,0 +1,372 @@
+package msql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// FakeDataBase 是注册为数据库别名的内存假驱动，用于在没有真实数据库时测试基于 Builder 的代码。
+//
+// FakeDataBase 会记录每条实际发送给驱动的 SQL 和参数，并按 Expect 注册的规则返回预设结果或错误。
+// 占位符风格由注册时的 driverName 决定：DriverMysql 使用 ?，DriverPostgres 使用 $1、$2。
+//
+// 示例：
+//
+//	fake, err := msql.RegisterFakeDataBase("test", msql.DriverPostgres)
+//	fake.Expect(`^select \* from users`).WillReturnRows([]string{"id", "name"}, []any{1, "tom"})
+//	user, err := msql.Model("users", "test").Where("id", "=", "1").Find()
+//	q, _ := fake.LastQuery() // q.Query == "select * from users where id=$1 limit 1"
+type FakeDataBase struct {
+	mu      sync.Mutex
+	queries []FakeQuery
+	rules   []*FakeRule
+}
+
+// FakeQuery 表示 FakeDataBase 记录的一次驱动调用。
+type FakeQuery struct {
+	// Query 为最终发送给驱动的 SQL，事务边界记录为 BEGIN、COMMIT、ROLLBACK。
+	Query string
+	// Args 为按顺序传给驱动的绑定参数。
+	Args []any
+	// Exec 为 true 表示通过 Exec 执行，false 表示通过 Query 执行。
+	Exec bool
+	// Tx 表示该调用是否发生在事务内。
+	Tx bool
+}
+
+// FakeRule 表示一条匹配 SQL 后返回预设结果的规则。
+//
+// 规则按 Expect 注册顺序匹配，第一条匹配且未用尽次数的规则生效；没有匹配规则时，
+// 查询返回空结果集，写入返回 LastInsertId 和 RowsAffected 都为 0 的结果。
+type FakeRule struct {
+	pattern  *regexp.Regexp
+	columns  []string
+	rows     [][]any
+	lastID   int64
+	affected int64
+	err      error
+	times    int
+	used     int
+}
+
+// RegisterFakeDataBase 注册一个使用内存假驱动的数据库别名，并返回其记录器。
+//
+// name 为空时使用 default 作为别名；driverName 为空时默认使用 DriverMysql，
+// 仅支持 DriverMysql 和 DriverPostgres，用于决定 Builder 渲染占位符的方式。
+// 与 RegisterDataBase 相同，同一别名只能注册一次，可通过 CloseAllRegDataBase 注销。
+func RegisterFakeDataBase(name string, driverName ...string) (*FakeDataBase, error) {
+	var emptyName bool
+	if name == "" {
+		emptyName = true
+	}
+	name = registeredAliasName(name)
+	if isDataBaseRegistered(name) {
+		return nil, duplicateAliasError(emptyName)
+	}
+	driverType := DriverMysql
+	if len(driverName) > 0 && len(driverName[0]) > 0 {
+		driverType = driverName[0]
+	}
+	if driverType != DriverMysql && driverType != DriverPostgres {
+		return nil, fmt.Errorf("the fake database does not support driver %q", driverType)
+	}
+	fake := &FakeDataBase{}
+	alias := &dataBase{
+		name:   name,
+		conn:   "fake",
+		driver: driverType,
+		life:   time.Second * 10,
+		open:   50,
+		idle:   25,
+		db:     sql.OpenDB(&fakeConnector{fake: fake}),
+	}
+	if !insertDataBaseAlias(name, alias) {
+		_ = closeAliasDB(alias)
+		return nil, duplicateAliasError(emptyName)
+	}
+	return fake, nil
+}
+
+// Expect 注册一条按正则匹配 SQL 的规则。
+//
+// pattern 使用 regexp 语法匹配最终发送给驱动的 SQL，非法正则会 panic。
+func (f *FakeDataBase) Expect(pattern string) *FakeRule {
+	rule := &FakeRule{pattern: regexp.MustCompile(pattern)}
+	f.mu.Lock()
+	f.rules = append(f.rules, rule)
+	f.mu.Unlock()
+	return rule
+}
+
+// Queries 返回当前已记录的全部驱动调用副本。
+func (f *FakeDataBase) Queries() []FakeQuery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	queries := make([]FakeQuery, len(f.queries))
+	copy(queries, f.queries)
+	return queries
+}
+
+// LastQuery 返回最近一次记录的驱动调用；没有记录时第二个返回值为 false。
+func (f *FakeDataBase) LastQuery() (FakeQuery, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		return FakeQuery{}, false
+	}
+	return f.queries[len(f.queries)-1], true
+}
+
+// Reset 清空已记录的驱动调用和已注册的规则。
+func (f *FakeDataBase) Reset() {
+	f.mu.Lock()
+	f.queries = nil
+	f.rules = nil
+	f.mu.Unlock()
+}
+
+// WillReturnRows 设置匹配查询返回的列名和行数据。
+//
+// 行数据会按 database/sql/driver 的默认规则转换，无法转换的值使用 fmt.Sprint 的结果。
+func (r *FakeRule) WillReturnRows(columns []string, rows ...[]any) *FakeRule {
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// WillReturnResult 设置匹配写入返回的 LastInsertId 和 RowsAffected。
+func (r *FakeRule) WillReturnResult(lastInsertID, rowsAffected int64) *FakeRule {
+	r.lastID = lastInsertID
+	r.affected = rowsAffected
+	return r
+}
+
+// WillReturnError 设置匹配调用返回的错误。
+func (r *FakeRule) WillReturnError(err error) *FakeRule {
+	r.err = err
+	return r
+}
+
+// Times 限制规则最多生效 n 次；n 小于 1 表示不限制。
+func (r *FakeRule) Times(n int) *FakeRule {
+	r.times = n
+	return r
+}
+
+// record 记录一次驱动调用，并返回第一条匹配且未用尽次数的规则。
+func (f *FakeDataBase) record(query string, args []driver.NamedValue, exec, inTx bool) *FakeRule {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, FakeQuery{Query: query, Args: values, Exec: exec, Tx: inTx})
+	for _, rule := range f.rules {
+		if rule.times > 0 && rule.used >= rule.times {
+			continue
+		}
+		if rule.pattern.MatchString(query) {
+			rule.used++
+			return rule
+		}
+	}
+	return nil
+}
+
+// fakeConnector 为 sql.OpenDB 提供假驱动连接。
+type fakeConnector struct {
+	fake *FakeDataBase
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{fake: c.fake}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver 仅用于满足 driver.Connector 接口，不支持通过 DSN 打开连接。
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("the fake driver can only be opened by RegisterFakeDataBase")
+}
+
+// fakeConn 表示一个假驱动物理连接，并记录当前是否处于事务中。
+type fakeConn struct {
+	fake *FakeDataBase
+	inTx bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if rule := c.fake.record("BEGIN", nil, true, false); rule != nil && rule.err != nil {
+		return nil, rule.err
+	}
+	c.inTx = true
+	return &fakeTx{conn: c}, nil
+}
+
+// CheckNamedValue 接受任意参数值，确保记录下来的是调用方传入的原始参数。
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(query, args)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.query(query, args)
+}
+
+func (c *fakeConn) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	rule := c.fake.record(query, args, true, c.inTx)
+	if rule == nil {
+		return fakeResult{}, nil
+	}
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return fakeResult{lastID: rule.lastID, affected: rule.affected}, nil
+}
+
+func (c *fakeConn) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	rule := c.fake.record(query, args, false, c.inTx)
+	if rule == nil {
+		return &fakeRows{}, nil
+	}
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return &fakeRows{columns: rule.columns, rows: rule.rows}, nil
+}
+
+// fakeTx 在事务结束时记录 COMMIT 或 ROLLBACK。
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	return t.finish("COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.finish("ROLLBACK")
+}
+
+func (t *fakeTx) finish(query string) error {
+	t.conn.inTx = false
+	if rule := t.conn.fake.record(query, nil, true, true); rule != nil {
+		return rule.err
+	}
+	return nil
+}
+
+// fakeStmt 表示预处理语句，执行时才记录 SQL 和参数。
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.exec(s.query, toFakeNamedValues(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.query(s.query, toFakeNamedValues(args))
+}
+
+func (s *fakeStmt) ExecContext(_ context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.exec(s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(_ context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.query(s.query, args)
+}
+
+// toFakeNamedValues 将旧式位置参数转换为 NamedValue。
+func toFakeNamedValues(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+// fakeResult 保存规则预设的写入结果。
+type fakeResult struct {
+	lastID   int64
+	affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+// fakeRows 按顺序返回规则预设的行数据。
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+	index   int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.index]
+	r.index++
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(row[i])
+		if err != nil {
+			value = fmt.Sprint(row[i])
+		}
+		dest[i] = value
+	}
+	return nil
+}
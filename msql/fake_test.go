@@ -0,0 +1,113 @@
+package msql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestFakeDataBase 以测试名注册假驱动别名，并在测试结束时注销。
+func newTestFakeDataBase(t *testing.T, driverName string) (string, *FakeDataBase) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	fake, err := RegisterFakeDataBase(name, driverName)
+	if err != nil {
+		t.Fatalf("RegisterFakeDataBase(%q) error: %v", name, err)
+	}
+	t.Cleanup(func() {
+		_ = closeAliasDB(removeDataBaseAlias(name))
+	})
+	return name, fake
+}
+
+func TestFakeDataBasePlaceholders(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{DriverMysql, "select * from users where id=? and status=? limit 1"},
+		{DriverPostgres, "select * from users where id=$1 and status=$2 limit 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, tt.driver)
+			fake.Expect(`^select \* from users`).WillReturnRows([]string{"id", "name"}, []any{1, "tom"})
+			user, err := Model("users", name).Where("id", "=", "1").Where("status", "=", "on").Find()
+			if err != nil {
+				t.Fatalf("Find() error: %v", err)
+			}
+			if user["name"] != "tom" {
+				t.Errorf("Find() name = %q, want %q", user["name"], "tom")
+			}
+			q, ok := fake.LastQuery()
+			if !ok {
+				t.Fatal("LastQuery() recorded nothing")
+			}
+			if q.Query != tt.want {
+				t.Errorf("query = %q, want %q", q.Query, tt.want)
+			}
+			if want := []any{"1", "on"}; !reflect.DeepEqual(q.Args, want) {
+				t.Errorf("args = %v, want %v", q.Args, want)
+			}
+			if q.Exec || q.Tx {
+				t.Errorf("query Exec = %v, Tx = %v, want false, false", q.Exec, q.Tx)
+			}
+		})
+	}
+}
+
+func TestFakeDataBaseRules(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	errBoom := errors.New("boom")
+	fake.Expect(`^update users`).WillReturnResult(0, 3).Times(1)
+	fake.Expect(`^update users`).WillReturnError(errBoom)
+
+	tests := []struct {
+		rows    int64
+		wantErr error
+	}{
+		{rows: 3},
+		{wantErr: errBoom},
+	}
+	for i, tt := range tests {
+		rows, err := Model("users", name).Where("id", "=", "1").Update(Datas{"name": "tom"})
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("call %d: Update() error = %v, want %v", i, err, tt.wantErr)
+		}
+		if rows != tt.rows {
+			t.Errorf("call %d: Update() rows = %d, want %d", i, rows, tt.rows)
+		}
+	}
+	if got := len(fake.Queries()); got != 2 {
+		t.Errorf("recorded %d queries, want 2", got)
+	}
+	fake.Reset()
+	if _, ok := fake.LastQuery(); ok {
+		t.Error("LastQuery() after Reset reported a query")
+	}
+}
+
+func TestFakeDataBaseTransaction(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	m := Model("users", name)
+	if err := m.Begin(); err != nil {
+		t.Fatalf("Begin() error: %v", err)
+	}
+	if _, err := m.Insert(Datas{"name": "tom"}); err != nil {
+		t.Fatalf("Insert() error: %v", err)
+	}
+	if err := m.Rollback(); err != nil {
+		t.Fatalf("Rollback() error: %v", err)
+	}
+	var got []string
+	for _, q := range fake.Queries() {
+		got = append(got, strings.Fields(q.Query)[0])
+		if strings.HasPrefix(q.Query, "insert") && !q.Tx {
+			t.Errorf("query %q recorded outside the transaction", q.Query)
+		}
+	}
+	if want := []string{"BEGIN", "insert", "ROLLBACK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queries = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,505 @@
+// Package fixtures 为 msql 注册的数据库连接加载测试和演示数据，并支持把表数据导出为 fixture 文件。
+//
+// fixture 文件使用 YAML 或 JSON，顶层 key 为表名，第二层 key 为行标签，第三层为字段和值：
+//
+//	users:
+//	  alice:
+//	    name: Alice
+//	    created_at: '{{ now }}'
+//	  bob:
+//	    name: Bob
+//	    created_at: '{{ now "-24h" }}'
+//	orders:
+//	  first:
+//	    no: 'NO-{{ seq "order" }}'
+//	    user_id: '{{ ref "users.alice" }}'
+//
+// 字符串值中可以使用 text/template 语法和以下函数：
+//
+//	now [offset]      本次加载的统一时间，格式为 2006-01-02 15:04:05，offset 使用 time.ParseDuration 格式
+//	seq [name]        按名称递增的序号，每次加载从 1 开始
+//	ref "table.label" 引用其它 fixture 行的主键，ref "table.label.field" 引用该行指定字段
+//
+// Apply 会根据 ref 计算表之间的依赖顺序，在同一事务中按逆序清空表、按顺序插入数据。
+// 行标签只用于引用和排序，不会写入数据库。
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zhimaAi/go_tools/msql"
+	"gopkg.in/yaml.v2"
+)
+
+// Fixtures 表示一组已加载的 fixture 数据。
+type Fixtures struct {
+	tables      map[string]map[string]map[string]any
+	primaryKeys map[string]string
+}
+
+// Load 从 YAML 或 JSON 文件加载 fixture 数据。
+//
+// paths 可以是文件或目录，目录会按文件名顺序加载其中的 .yaml、.yml 和 .json 文件。
+// 多个文件可以包含同一张表，但同一张表中的行标签不能重复。
+//
+// 示例：
+//
+//	fx, err := fixtures.Load("testdata/fixtures")
+//	if err == nil {
+//	    err = fx.Apply("")
+//	}
+func Load(paths ...string) (*Fixtures, error) {
+	f := &Fixtures{
+		tables:      make(map[string]map[string]map[string]any),
+		primaryKeys: make(map[string]string),
+	}
+	for _, path := range paths {
+		files, err := fixtureFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := f.loadFile(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// SetPrimaryKey 设置表的主键字段名，ref 默认引用该字段；未设置时使用 id。
+func (f *Fixtures) SetPrimaryKey(table, field string) *Fixtures {
+	f.primaryKeys[table] = field
+	return f
+}
+
+// Tables 返回按依赖顺序排列的表名，被引用的表排在前面。
+func (f *Fixtures) Tables() ([]string, error) {
+	deps, err := f.dependencies()
+	if err != nil {
+		return nil, err
+	}
+	return sortTables(deps)
+}
+
+// Apply 在 name 指定的连接上清空 fixture 涉及的表并插入全部数据。
+//
+// 所有操作在同一事务中执行：先按依赖逆序使用 delete 清空表，再按依赖顺序逐行 Insert，
+// 同一张表中的行按标签字典序插入，表内 ref 只能引用排序在前的行；任一步失败都会回滚。行中未提供主键时，
+// MySQL 使用自增 ID，PostgreSQL 使用 returning 读取主键，供后续 ref 引用。
+// 表的自增序列不会被重置。
+func (f *Fixtures) Apply(name string) error {
+	tables, err := f.Tables()
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	driver, err := msql.GetDriver(name)
+	if err != nil {
+		return err
+	}
+	m := msql.Model(tables[0], name)
+	if err := m.Begin(); err != nil {
+		return err
+	}
+	if err := f.apply(m, tables, driver); err != nil {
+		_ = m.Rollback()
+		return err
+	}
+	return m.Commit()
+}
+
+// apply 在已开启事务的 Builder 上执行清空和插入。
+func (f *Fixtures) apply(m *msql.Builder, tables []string, driver string) error {
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, err := m.Table(tables[i]).Where("1=1").Delete(); err != nil {
+			return fmt.Errorf("clear table %s: %w", tables[i], err)
+		}
+	}
+	render := newRenderer(time.Now())
+	for _, table := range tables {
+		pk := f.primaryKey(table)
+		for _, label := range sortedKeys(f.tables[table]) {
+			data := msql.Datas{}
+			row := f.tables[table][label]
+			for _, field := range sortedKeys(row) {
+				v, err := render.value(row[field])
+				if err != nil {
+					return fmt.Errorf("%s.%s.%s: %w", table, label, field, err)
+				}
+				data[field] = v
+			}
+			var returning []string
+			if _, ok := data[pk]; !ok && driver == msql.DriverPostgres {
+				returning = []string{pk}
+			}
+			id, err := m.Table(table).Insert(data, returning...)
+			if err != nil {
+				return fmt.Errorf("insert %s.%s: %w", table, label, err)
+			}
+			if _, ok := data[pk]; !ok {
+				data[pk] = id
+			}
+			render.inserted[table+"."+label] = rowRef{pk: pk, data: data}
+		}
+	}
+	return nil
+}
+
+// Snapshot 把 name 指定连接上 tables 的当前数据写入 fixture 文件。
+//
+// 文件格式按 path 扩展名判断，.json 写入 JSON，其余写入 YAML；行标签为 <表名>_<序号>。
+// 表存在 id 字段时按 id 排序。字段值统一按字符串导出，NULL 会导出为空字符串；
+// 值中的 {{ 会转义为 {{"{{"}}，Load 时按模板渲染后还原为原值。
+//
+// 示例：
+//
+//	err := fixtures.Snapshot("", "testdata/fixtures/users.yaml", "users", "orders")
+func Snapshot(name, path string, tables ...string) error {
+	out := make(map[string]map[string]msql.Params, len(tables))
+	for _, table := range tables {
+		m := msql.Model(table, name)
+		if ok, err := msql.Model(table, name).FieldExists("id"); err == nil && ok {
+			m.Order("id")
+		}
+		list, err := m.Select()
+		if err != nil {
+			return fmt.Errorf("snapshot table %s: %w", table, err)
+		}
+		rows := make(map[string]msql.Params, len(list))
+		width := len(strconv.Itoa(len(list)))
+		for i, row := range list {
+			for field, value := range row {
+				row[field] = escapeTemplate(value)
+			}
+			rows[fmt.Sprintf("%s_%0*d", table, width, i+1)] = row
+		}
+		out[table] = rows
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(out, "", "  ")
+	} else {
+		data, err = yaml.Marshal(out)
+	}
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadFile 解析单个 fixture 文件并合并到当前数据中。
+func (f *Fixtures) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]map[string]map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("parse fixture file %s: %w", path, err)
+	}
+	for table, rows := range raw {
+		if f.tables[table] == nil {
+			f.tables[table] = make(map[string]map[string]any, len(rows))
+		}
+		for label, row := range rows {
+			if _, ok := f.tables[table][label]; ok {
+				return fmt.Errorf("duplicate fixture %s.%s in %s", table, label, path)
+			}
+			values := make(map[string]any, len(row))
+			for field, value := range row {
+				values[field] = normalizeValue(value)
+			}
+			f.tables[table][label] = values
+		}
+	}
+	return nil
+}
+
+// dependencies 通过试运行模板收集每张表引用的其它表，并校验引用的行存在。
+func (f *Fixtures) dependencies() (map[string][]string, error) {
+	deps := make(map[string][]string, len(f.tables))
+	for table, rows := range f.tables {
+		seen := map[string]bool{}
+		deps[table] = nil
+		for label, row := range rows {
+			for field, value := range row {
+				s, ok := value.(string)
+				if !ok || !strings.Contains(s, "{{") {
+					continue
+				}
+				refs, err := templateRefs(s)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s.%s: %w", table, label, field, err)
+				}
+				for _, ref := range refs {
+					refTable, refLabel, _ := splitRef(ref)
+					if _, ok := f.tables[refTable][refLabel]; !ok {
+						return nil, fmt.Errorf("%s.%s.%s: fixture %q does not exist", table, label, field, ref)
+					}
+					if refTable != table && !seen[refTable] {
+						seen[refTable] = true
+						deps[table] = append(deps[table], refTable)
+					}
+				}
+			}
+		}
+	}
+	return deps, nil
+}
+
+// primaryKey 返回表的主键字段名。
+func (f *Fixtures) primaryKey(table string) string {
+	if pk := f.primaryKeys[table]; pk != "" {
+		return pk
+	}
+	return "id"
+}
+
+// rowRef 保存已插入行的数据，供 ref 引用。
+type rowRef struct {
+	pk   string
+	data msql.Datas
+}
+
+// renderer 渲染 fixture 字符串模板，并记录已插入的行和序号。
+type renderer struct {
+	now      time.Time
+	seqs     map[string]int
+	inserted map[string]rowRef
+	funcs    template.FuncMap
+}
+
+// newRenderer 创建使用统一时间 now 的模板渲染器。
+func newRenderer(now time.Time) *renderer {
+	r := &renderer{now: now, seqs: map[string]int{}, inserted: map[string]rowRef{}}
+	r.funcs = template.FuncMap{
+		"now": func(offset ...string) (string, error) {
+			t := r.now
+			if len(offset) > 0 {
+				d, err := time.ParseDuration(offset[0])
+				if err != nil {
+					return "", err
+				}
+				t = t.Add(d)
+			}
+			return t.Format(time.DateTime), nil
+		},
+		"seq": func(name ...string) int {
+			key := strings.Join(name, ".")
+			r.seqs[key]++
+			return r.seqs[key]
+		},
+		"ref": func(path string) (any, error) {
+			table, label, field := splitRef(path)
+			row, ok := r.inserted[table+"."+label]
+			if !ok {
+				return nil, fmt.Errorf("fixture %q has not been inserted yet", path)
+			}
+			if field == "" {
+				field = row.pk
+			}
+			v, ok := row.data[field]
+			if !ok {
+				return nil, fmt.Errorf("fixture %q has no field %s", path, field)
+			}
+			return v, nil
+		},
+	}
+	return r
+}
+
+// escapeTemplate 转义值中的模板起始符，使导出的原始数据不会在 Load 时被当作模板执行。
+func escapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "{{", `{{"{{"}}`)
+}
+
+// value 渲染单个字段值，只有包含模板语法的字符串会被执行。
+func (r *renderer) value(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok || !strings.Contains(s, "{{") {
+		return value, nil
+	}
+	tpl, err := template.New("").Funcs(r.funcs).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+// templateRefs 试运行模板并返回其中 ref 引用的全部路径。
+func templateRefs(s string) ([]string, error) {
+	var refs []string
+	funcs := template.FuncMap{
+		"now": func(...string) string { return "" },
+		"seq": func(...string) int { return 0 },
+		"ref": func(path string) string {
+			refs = append(refs, path)
+			return ""
+		},
+	}
+	tpl, err := template.New("").Funcs(funcs).Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := tpl.Execute(&bytes.Buffer{}, nil); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// splitRef 将 table.label[.field] 拆分为表名、行标签和字段名。
+func splitRef(path string) (table, label, field string) {
+	parts := strings.SplitN(path, ".", 3)
+	switch len(parts) {
+	case 3:
+		return parts[0], parts[1], parts[2]
+	case 2:
+		return parts[0], parts[1], ""
+	}
+	return path, "", ""
+}
+
+// sortTables 按依赖关系对表进行拓扑排序，同一层级按表名字典序排列；存在循环依赖时返回错误。
+func sortTables(deps map[string][]string) ([]string, error) {
+	pending := make(map[string]int, len(deps))
+	users := make(map[string][]string, len(deps))
+	for table, list := range deps {
+		pending[table] += 0
+		for _, dep := range list {
+			pending[table]++
+			users[dep] = append(users[dep], table)
+		}
+	}
+	var ready, order []string
+	for table, n := range pending {
+		if n == 0 {
+			ready = append(ready, table)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		table := ready[0]
+		ready = ready[1:]
+		order = append(order, table)
+		for _, user := range users[table] {
+			if pending[user]--; pending[user] == 0 {
+				ready = append(ready, user)
+			}
+		}
+	}
+	if len(order) != len(pending) {
+		var cycle []string
+		for table, n := range pending {
+			if n > 0 {
+				cycle = append(cycle, table)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, errors.New("circular fixture references between tables: " + strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// fixtureFiles 返回路径对应的 fixture 文件列表。
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// normalizeValue 把 YAML 解析出的嵌套 map 和数组编码为 JSON 字符串，标量值保持不变。
+func normalizeValue(value any) any {
+	switch value.(type) {
+	case map[any]any, map[string]any, []any:
+		b, err := json.Marshal(jsonCompatible(value))
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(b)
+	}
+	return value
+}
+
+// jsonCompatible 把 yaml.v2 的 map[any]any 递归转换为可 JSON 编码的 map[string]any。
+func jsonCompatible(value any) any {
+	switch v := value.(type) {
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[fmt.Sprint(k)] = jsonCompatible(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = jsonCompatible(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = jsonCompatible(item)
+		}
+		return out
+	}
+	return value
+}
+
+// sortedKeys 返回按字典序排列的 map key。
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
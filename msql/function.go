@@ -24,3 +24,19 @@ func InArray[T comparable](needle T, haystack []T) bool {
 	}
 	return false
 }
+
+// Raw 创建可放入 Datas 的原始 SQL 表达式值。
+//
+// Insert 和 Update 遇到 Raw 值时会把 expr 原样拼接到对应字段的值位置，args 按出现顺序
+// 与其它字段值一起绑定。expr 会原样拼接，调用方需保证内容可信；
+// PostgreSQL 下 expr 需要使用 $1、$2 等占位符，并会按最终 SQL 出现顺序重新编号；? 会按 SQL 原文保留。
+//
+// 示例：
+//
+//	rows, err := msql.Model("users").Where("id", "=", "1").Update(msql.Datas{
+//	    "login_count": msql.Raw("login_count + ?", 1),
+//	    "last_login":  time.Now(),
+//	})
+func Raw(expr string, args ...any) RawExpr {
+	return RawExpr{expr: expr, args: args}
+}
@@ -47,7 +47,14 @@ func (m *Builder) queryValues(rawQuery string, withField bool) ([]Params, error)
 	query := renderParamSeats(m.name, rawQuery, 0)
 	args := m.getQueryArgs(withField)
 	m.lastsql = renderDebugParamSeats(rawQuery, args)
-	return RawValues(m.name, query, m.tx, args...)
+	list, err := rawValuesContext(m.context(), m.name, query, m.tx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.decryptParams(list); err != nil {
+		return nil, err
+	}
+	return list, nil
 }
 
 // rawValues 执行已经构造完成的原始查询 SQL，并同步记录调试 SQL。
@@ -56,17 +63,20 @@ func (m *Builder) queryValues(rawQuery string, withField bool) ([]Params, error)
 // 不再经过 buildSql，因此直接使用该方法执行。
 func (m *Builder) rawValues(query string, args []any) ([]Params, error) {
 	m.lastsql = renderDebugParamSeats(query, args)
-	return RawValues(m.name, query, m.tx, args...)
+	return rawValuesContext(m.context(), m.name, query, m.tx, args)
 }
 
 // execRowsAffected 执行写入 SQL，并把影响行数保存到当前 Builder。
 //
 // Update、Update2 和 Delete 都只关心 RawExec 的 RowsAffected 结果，
-// 该方法用于统一 lastsql 记录、执行和 affect 更新逻辑。
+// 该方法用于统一 lastsql 记录、dry-run 拦截、执行和 affect 更新逻辑。
 func (m *Builder) execRowsAffected(query string, args []any) (int64, error) {
 	m.affect = 0
 	m.lastsql = renderDebugParamSeats(query, args)
-	ret, err := RawExec(m.name, query, m.tx, args...)
+	if m.recordDryRun(query, args) {
+		return 0, nil
+	}
+	ret, err := rawExecContext(m.context(), m.name, query, m.tx, args)
 	if err != nil {
 		return 0, err
 	}
@@ -78,6 +88,35 @@ func (m *Builder) execRowsAffected(query string, args []any) (int64, error) {
 	return rows, nil
 }
 
+// execInsert 执行已经渲染完成的 insert SQL，并把记录 ID 保存到当前 Builder。
+//
+// 指定 returning 时通过查询读取返回字段，否则 MySQL 使用驱动返回的自增 ID。
+func (m *Builder) execInsert(query string, values []any, returning []string) (int64, error) {
+	if len(returning) > 0 { // 兼容 PostgreSQL returning。
+		if vs, err := rawValuesContext(m.context(), m.name, query, m.tx, values); err == nil {
+			if len(vs) > 0 {
+				m.lastid, _ = strconv.ParseInt(vs[0][returning[0]], 10, 64)
+			}
+			return m.lastid, nil
+		} else {
+			return 0, err
+		}
+	}
+	if ret, err := rawExecContext(m.context(), m.name, query, m.tx, values); err == nil {
+		if isPostgres(m.name) {
+			return 0, nil
+		}
+		id, err := ret.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		m.lastid = id
+		return id, nil
+	} else {
+		return 0, err
+	}
+}
+
 // getFields 生成 select 字段列表。
 //
 // 未指定字段时返回 *。
@@ -88,9 +127,21 @@ func (m *Builder) getFields() string {
 	return strings.Join(m.field, ",")
 }
 
+// baseTableName 返回表名字符串中的第一个表名，去掉表别名和包裹引号。
+//
+// 例如 "users u" 返回 "users"，用于按表名匹配审计等表级配置。
+func baseTableName(table string) string {
+	fields := strings.Fields(ToField(table))
+	if len(fields) == 0 {
+		return ""
+	}
+	return ToField(fields[0])
+}
+
 // tableName 返回当前 Builder 的有效表名。
 //
 // 表名会复用 ToField 的清理规则；空 Builder、零值 Builder 或仅包含空白/引号的表名都会返回错误。
+// 通过 Schema 设置了 schema 且表名未带 schema 前缀时，返回值会带上 schema 前缀。
 func (m *Builder) tableName() (string, error) {
 	if m == nil {
 		return "", errEmptyTableName
@@ -99,18 +150,22 @@ func (m *Builder) tableName() (string, error) {
 	if table == "" {
 		return "", errEmptyTableName
 	}
+	if m.schema != "" && !strings.Contains(baseTableName(table), ".") {
+		table = m.schema + "." + table
+	}
 	return table, nil
 }
 
 // getQueryArgs 按最终 SQL 出现顺序返回查询绑定参数。
 //
-// withField 为 false 时不包含字段表达式参数，主要用于 count 查询。
+// withField 为 false 时不包含字段表达式参数，主要用于 count 查询；CTE 参数总是排在最前面。
 func (m *Builder) getQueryArgs(withField bool) []any {
-	capacity := len(m.joinArgs) + len(m.whereArgs) + len(m.whereorArgs) + len(m.havingArgs)
+	capacity := len(m.withArgs) + len(m.joinArgs) + len(m.whereArgs) + len(m.whereorArgs) + len(m.havingArgs)
 	if withField {
 		capacity += len(m.fieldArgs)
 	}
 	args := make([]any, 0, capacity)
+	args = append(args, m.withArgs...)
 	if withField {
 		args = append(args, m.fieldArgs...)
 	}
@@ -182,6 +237,7 @@ func (m *Builder) buildSql() (string, error) {
 		return "", err
 	}
 	return joinSQLParts(
+		m.getWith(),
 		"select",
 		m.getFields(),
 		"from",
@@ -198,7 +254,7 @@ func (m *Builder) buildSql() (string, error) {
 
 // buildCount 生成当前条件对应的 count SQL。
 //
-// 存在 group by 时会包装为子查询后再统计总数。
+// 存在 group by 时会包装为子查询后再统计总数，with 子句始终位于最外层。
 func (m *Builder) buildCount(field string) (string, error) {
 	table, err := m.tableName()
 	if err != nil {
@@ -223,7 +279,7 @@ func (m *Builder) buildCount(field string) (string, error) {
 		//noinspection SqlDialectInspection
 		query = "select count(*) total from (" + query + ") gc"
 	}
-	query += " limit 1"
+	query = joinSQLParts(m.getWith(), query, "limit 1")
 	return query, nil
 }
 
@@ -270,6 +326,48 @@ func sortedDataKeys(data Datas) []string {
 	return keys
 }
 
+// dataValueSeat 返回 Datas 字段值在 SQL 中的占位片段和绑定参数。
+//
+// 普通值使用包内临时占位符；Raw 表达式原样返回表达式文本和其参数。
+func dataValueSeat(value any) (string, []any) {
+	switch v := value.(type) {
+	case RawExpr:
+		return v.expr, v.args
+	case *RawExpr:
+		if v != nil {
+			return v.expr, v.args
+		}
+	}
+	return paramSeat, []any{value}
+}
+
+// isRawExpr 判断 Datas 字段值是否为 Raw 表达式。
+func isRawExpr(value any) bool {
+	switch v := value.(type) {
+	case RawExpr:
+		return true
+	case *RawExpr:
+		return v != nil
+	}
+	return false
+}
+
+// incrementBy 以 Raw 表达式更新计数字段，并合并调用方传入的其它字段。
+func (m *Builder) incrementBy(field, operator string, step any, data []Datas) (int64, error) {
+	field = ToField(field)
+	if field == "" {
+		return 0, errors.New("the field name cannot be empty")
+	}
+	merged := Datas{}
+	for _, d := range data {
+		for k, v := range d {
+			merged[k] = v
+		}
+	}
+	merged[field] = Raw(field+" "+operator+" "+paramSeat, step)
+	return m.Update(merged)
+}
+
 // sqlOpen 根据数据库配置打开连接并初始化连接池参数。
 func sqlOpen(alias *dataBase, driverName ...string) error {
 	var driver = DriverMysql
@@ -277,7 +375,15 @@ func sqlOpen(alias *dataBase, driverName ...string) error {
 		driver = driverName[0]
 	}
 	alias.driver = driver
-	db, err := sql.Open(driver, alias.conn)
+	var (
+		db  *sql.DB
+		err error
+	)
+	if alias.session != nil {
+		db, err = openSessionDB(driver, alias.conn, alias.session)
+	} else {
+		db, err = sql.Open(driver, alias.conn)
+	}
 	if err != nil {
 		return err
 	}
@@ -337,14 +443,19 @@ func useDataBaseAlias(name string, use func(*dataBase)) error {
 	return errors.New("the database alias does not exist")
 }
 
-// closeAliasDB 关闭别名持有的连接池；别名为空或连接为空时视为已关闭。
+// closeAliasDB 关闭别名缓存的预处理语句和连接池；别名为空或连接为空时视为已关闭。
 func closeAliasDB(alias *dataBase) error {
 	if alias == nil {
 		return nil
 	}
-	alias.mu.RLock()
+	alias.mu.Lock()
 	db := alias.db
-	alias.mu.RUnlock()
+	stmts := alias.stmts
+	alias.stmts = nil
+	alias.mu.Unlock()
+	if stmts != nil {
+		stmts.close()
+	}
 	if db == nil {
 		return nil
 	}
@@ -407,6 +518,24 @@ func setAliasDebug(alias *dataBase, dev bool) {
 	alias.mu.Unlock()
 }
 
+// setAliasSlowQuery 设置别名级慢查询阈值和处理函数。
+func setAliasSlowQuery(alias *dataBase, threshold time.Duration, handler func(SlowQuery)) {
+	alias.mu.Lock()
+	if threshold < 0 {
+		threshold = 0
+	}
+	alias.slow = threshold
+	alias.slowHandler = handler
+	alias.mu.Unlock()
+}
+
+// aliasSlowQuery 读取别名级慢查询阈值和处理函数。
+func aliasSlowQuery(alias *dataBase) (time.Duration, func(SlowQuery)) {
+	alias.mu.RLock()
+	defer alias.mu.RUnlock()
+	return alias.slow, alias.slowHandler
+}
+
 // insertDataBaseAlias 在全局注册表中原子插入别名。
 //
 // 返回 false 表示别名已被其它调用注册，调用方应关闭新建连接并返回重复错误。
@@ -420,6 +549,17 @@ func insertDataBaseAlias(name string, alias *dataBase) bool {
 	return true
 }
 
+// removeDataBaseAlias 从全局注册表中移除别名，并返回被移除的别名配置。
+//
+// RegisterConfig 注册失败回滚时使用，调用方负责关闭返回别名持有的连接。
+func removeDataBaseAlias(name string) *dataBase {
+	dataBasesMu.Lock()
+	defer dataBasesMu.Unlock()
+	alias := dataBases[name]
+	delete(dataBases, name)
+	return alias
+}
+
 // takeDataBaseAliases 取走当前全部注册连接，并把全局注册表重置为空。
 //
 // CloseAllRegDataBase 先取走 map 再逐个关闭连接，避免关闭数据库连接时长期占用全局锁。
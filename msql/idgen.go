@@ -0,0 +1,185 @@
+package msql
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ID 生成器的位分配：41 位毫秒时间戳、10 位机器 ID、12 位毫秒内序号。
+const (
+	idWorkerBits   = 10
+	idSequenceBits = 12
+	idMaxWorker    = 1<<idWorkerBits - 1
+	idMaxSequence  = 1<<idSequenceBits - 1
+	idMaxTimestamp = 1<<41 - 1
+	// idMaxBackward 为可等待追平的最大时钟回拨幅度，超过时 Next 返回错误。
+	idMaxBackward = 10 * time.Millisecond
+)
+
+// defaultIDEpoch 为未指定起始时间时使用的纪元，41 位毫秒时间戳可使用约 69 年。
+var defaultIDEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrClockBackwards 表示系统时钟回拨超过 ID 生成器可等待的范围。
+var ErrClockBackwards = errors.New("the clock moved backwards")
+
+// IDGenerator 生成按时间递增的 64 位分布式 ID（snowflake 风格），可安全地被多个 goroutine 共享。
+//
+// 不同进程必须使用不同的机器 ID，否则可能生成重复 ID。
+type IDGenerator struct {
+	mu       sync.Mutex
+	epoch    int64
+	worker   int64
+	lastTime int64
+	sequence int64
+}
+
+// NewIDGenerator 创建 ID 生成器。
+//
+// workerID 取值范围为 0~1023；epoch 为时间戳起点，零值时使用 2024-01-01 UTC，不能晚于当前时间。
+//
+// 示例：
+//
+//	gen, err := msql.NewIDGenerator(1, time.Time{})
+//	id, err := gen.Next()
+func NewIDGenerator(workerID int64, epoch time.Time) (*IDGenerator, error) {
+	if workerID < 0 || workerID > idMaxWorker {
+		return nil, fmt.Errorf("the worker id must be between 0 and %d", idMaxWorker)
+	}
+	if epoch.IsZero() {
+		epoch = defaultIDEpoch
+	}
+	if epoch.After(time.Now()) {
+		return nil, errors.New("the id epoch cannot be in the future")
+	}
+	return &IDGenerator{epoch: epoch.UnixMilli(), worker: workerID, lastTime: -1}, nil
+}
+
+// Next 生成下一个 ID。
+//
+// 同一毫秒内序号用尽时会等待到下一毫秒；时钟回拨不超过 10ms 时等待时钟追平，
+// 超过时返回 ErrClockBackwards，避免生成重复 ID。
+func (g *IDGenerator) Next() (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now().UnixMilli() - g.epoch
+	if now < g.lastTime {
+		backward := time.Duration(g.lastTime-now) * time.Millisecond
+		if backward > idMaxBackward {
+			return 0, fmt.Errorf("%w by %s", ErrClockBackwards, backward)
+		}
+		time.Sleep(backward)
+		now = g.waitAfter(g.lastTime - 1)
+	}
+	if now == g.lastTime {
+		g.sequence = (g.sequence + 1) & idMaxSequence
+		if g.sequence == 0 {
+			now = g.waitAfter(g.lastTime)
+		}
+	} else {
+		g.sequence = 0
+	}
+	if now > idMaxTimestamp {
+		return 0, errors.New("the id timestamp overflowed, use a later epoch")
+	}
+	g.lastTime = now
+	return now<<(idWorkerBits+idSequenceBits) | g.worker<<idSequenceBits | g.sequence, nil
+}
+
+// Decompose 解析由当前生成器纪元生成的 ID，返回生成时间、机器 ID 和毫秒内序号。
+func (g *IDGenerator) Decompose(id int64) (t time.Time, workerID, sequence int64) {
+	ms := id >> (idWorkerBits + idSequenceBits)
+	return time.UnixMilli(ms + g.epoch), id >> idSequenceBits & idMaxWorker, id & idMaxSequence
+}
+
+// waitAfter 自旋等待到相对纪元的毫秒时间戳大于 last，并返回新的时间戳。
+func (g *IDGenerator) waitAfter(last int64) int64 {
+	now := time.Now().UnixMilli() - g.epoch
+	for now <= last {
+		time.Sleep(100 * time.Microsecond)
+		now = time.Now().UnixMilli() - g.epoch
+	}
+	return now
+}
+
+// idHook 表示表级主键自动生成配置。
+type idHook struct {
+	field string
+	gen   *IDGenerator
+}
+
+// SetIDGenerator 为指定连接上的表开启主键自动生成。
+//
+// 开启后，该表的 Insert 在 Datas 中没有 field 字段（或值为 nil）时，会先用 gen 生成 ID 写入 field，
+// 并把该 ID 作为 Insert 的返回值和 GetLastInsertId 的结果，PostgreSQL 无需再传 returning。
+// field 为空时使用 id。table 按 Model 传入的第一个表名匹配，不包含表别名。
+//
+// 示例：
+//
+//	gen, _ := msql.NewIDGenerator(workerID, time.Time{})
+//	err := msql.SetIDGenerator("pg", "orders", "id", gen)
+//	id, err := msql.Model("orders", "pg").Insert(msql.Datas{"amount": 100})
+func SetIDGenerator(name, table, field string, gen *IDGenerator) error {
+	table = baseTableName(table)
+	if table == "" {
+		return errEmptyTableName
+	}
+	if gen == nil {
+		return errors.New("the id generator cannot be nil")
+	}
+	if field = ToField(field); field == "" {
+		field = "id"
+	}
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		if alias.idHooks == nil {
+			alias.idHooks = make(map[string]*idHook)
+		}
+		alias.idHooks[table] = &idHook{field: field, gen: gen}
+		alias.mu.Unlock()
+	})
+}
+
+// RemoveIDGenerator 关闭指定连接上表的主键自动生成。
+func RemoveIDGenerator(name, table string) error {
+	table = baseTableName(table)
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		delete(alias.idHooks, table)
+		alias.mu.Unlock()
+	})
+}
+
+// generateID 在当前表开启主键自动生成且 data 缺少主键时，返回补齐主键的新 Datas 和生成的 ID。
+//
+// 未生成 ID 时返回原 data 和 0，不会修改传入的 data。
+func (m *Builder) generateID(data Datas) (Datas, int64, error) {
+	alias, ok := lookupDataBase(m.name)
+	if !ok || alias == nil {
+		return data, 0, nil
+	}
+	alias.mu.RLock()
+	hook := alias.idHooks[baseTableName(m.table)]
+	alias.mu.RUnlock()
+	if hook == nil {
+		return data, 0, nil
+	}
+	for k, v := range data {
+		if ToField(k) == hook.field && v != nil {
+			return data, 0, nil
+		}
+	}
+	id, err := hook.gen.Next()
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make(Datas, len(data)+1)
+	for k, v := range data {
+		if ToField(k) != hook.field {
+			out[k] = v
+		}
+	}
+	out[hook.field] = id
+	return out, id, nil
+}
@@ -0,0 +1,118 @@
+package msql
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewIDGenerator(t *testing.T) {
+	tests := []struct {
+		worker  int64
+		epoch   time.Time
+		wantErr bool
+	}{
+		{0, time.Time{}, false},
+		{idMaxWorker, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{-1, time.Time{}, true},
+		{idMaxWorker + 1, time.Time{}, true},
+		{1, time.Now().Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		if _, err := NewIDGenerator(tt.worker, tt.epoch); (err != nil) != tt.wantErr {
+			t.Errorf("NewIDGenerator(%d, %v) error = %v, want error %v", tt.worker, tt.epoch, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIDGeneratorUniqueAndDecompose(t *testing.T) {
+	gen, err := NewIDGenerator(7, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const workers, perWorker = 8, 2000
+	var (
+		mu   sync.Mutex
+		seen = make(map[int64]bool, workers*perWorker)
+		wg   sync.WaitGroup
+	)
+	start := time.Now().Add(-time.Millisecond)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := int64(0)
+			for j := 0; j < perWorker; j++ {
+				id, err := gen.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if id <= last {
+					t.Errorf("Next() = %d after %d, want increasing ids", id, last)
+				}
+				last = id
+				mu.Lock()
+				if seen[id] {
+					t.Errorf("Next() returned duplicate id %d", id)
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for id := range seen {
+		ts, worker, sequence := gen.Decompose(id)
+		if worker != 7 || sequence > idMaxSequence || ts.Before(start.Truncate(time.Millisecond)) || ts.After(time.Now()) {
+			t.Fatalf("Decompose(%d) = %v, %d, %d", id, ts, worker, sequence)
+		}
+	}
+}
+
+func TestIDGeneratorInsert(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverPostgres)
+	gen, _ := NewIDGenerator(1, time.Time{})
+	if err := SetIDGenerator(name, "orders o", "", gen); err != nil {
+		t.Fatalf("SetIDGenerator() error: %v", err)
+	}
+	tests := []struct {
+		data      Datas
+		generated bool
+	}{
+		{Datas{"amount": 100}, true},
+		{Datas{"id": nil, "amount": 100}, true},
+		{Datas{"id": 42, "amount": 100}, false},
+	}
+	for _, tt := range tests {
+		id, err := Model("orders", name).Insert(tt.data)
+		if err != nil {
+			t.Fatalf("Insert(%v) error: %v", tt.data, err)
+		}
+		q, _ := fake.LastQuery()
+		if !tt.generated {
+			if id != 0 {
+				t.Errorf("Insert(%v) = %d, want 0 from the fake driver", tt.data, id)
+			}
+			continue
+		}
+		if _, worker, _ := gen.Decompose(id); id <= 0 || worker != 1 {
+			t.Errorf("Insert(%v) = %d, want a generated id", tt.data, id)
+		}
+		var found bool
+		for _, arg := range q.Args {
+			if arg == id {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Insert(%v) args = %v, want them to contain id %d", tt.data, q.Args, id)
+		}
+	}
+	if err := RemoveIDGenerator(name, "orders"); err != nil {
+		t.Fatalf("RemoveIDGenerator() error: %v", err)
+	}
+	if id, _ := Model("orders", name).Insert(Datas{"amount": 1}); id != 0 {
+		t.Errorf("Insert() after RemoveIDGenerator = %d, want 0 from the fake driver", id)
+	}
+}
@@ -0,0 +1,263 @@
+package msql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WhereJSON 添加 JSON 字段路径比较条件，path 和 value 都会按绑定参数传递。
+//
+// path 使用点号分隔的键路径，纯数字段表示数组下标，可带 $. 前缀，例如 profile.city、tags.0、$.tags[0]。
+// operator 支持 =、!=、<>、>、>=、<、<=、like 和 not like；like 会与 Where 一样自动包裹为 %value%。
+// MySQL 渲染为 JSON_UNQUOTE(JSON_EXTRACT(field, path))，PostgreSQL 渲染为 field #>> path；
+// PostgreSQL 下数值 value 会把提取结果转换为 numeric 后比较，bool value 按 true/false 文本比较。
+// field 会原样拼接，调用方需保证字段表达式可信；field、path 为空或 operator 不支持时不会追加条件。
+//
+// 方言在调用时按 Builder 当前别名判断，因此应先注册数据库别名再构造条件。
+//
+// 示例：
+//
+//	msql.Model("users").WhereJSON("profile", "address.city", "=", "Hangzhou")
+//	msql.Model("users", "pg").WhereJSON("settings", "quota", ">", 100)
+func (m *Builder) WhereJSON(field, path, operator string, value any) *Builder {
+	keys := splitJSONPath(path)
+	if field == "" || len(keys) == 0 {
+		return m
+	}
+	operator = strings.ToLower(strings.TrimSpace(operator))
+	switch {
+	case operator == "like" || operator == "not like":
+		value = "%" + fmt.Sprint(value) + "%"
+	case InArray(operator, []string{">", ">=", "<", "<=", "!=", "<>", "="}):
+	default:
+		return m
+	}
+	if b, ok := value.(bool); ok {
+		value = fmt.Sprint(b)
+	}
+	var expr string
+	if isPostgres(m.name) {
+		expr = "(" + field + " #>> " + paramSeat + "::text[])"
+		if isJSONNumber(value) {
+			expr += "::numeric"
+		}
+		return m.WhereRaw(expr+" "+operator+" "+paramSeat, postgresJSONPath(keys), value)
+	}
+	expr = "JSON_UNQUOTE(JSON_EXTRACT(" + field + ", " + paramSeat + "))"
+	return m.WhereRaw(expr+" "+operator+" "+paramSeat, mysqlJSONPath(keys), value)
+}
+
+// WhereJSONContains 添加 JSON 包含条件，判断 field 在 path 位置的 JSON 值是否包含 value。
+//
+// path 为空表示整个 JSON 文档，路径规则与 WhereJSON 一致。value 会先按 JSON 编码后绑定；
+// json.RawMessage 和 []byte 视为已经编码好的 JSON 原文。
+// MySQL 渲染为 JSON_CONTAINS，PostgreSQL 渲染为 jsonb 的 @> 运算符，因此 PostgreSQL 字段应为 jsonb 类型。
+// field 会原样拼接，调用方需保证字段表达式可信；field 为空时不会追加条件。
+//
+// 示例：
+//
+//	msql.Model("users").WhereJSONContains("tags", "", []string{"vip"})
+//	msql.Model("users", "pg").WhereJSONContains("profile", "roles", json.RawMessage(`["admin"]`))
+func (m *Builder) WhereJSONContains(field, path string, value any) *Builder {
+	if field == "" {
+		return m
+	}
+	keys := splitJSONPath(path)
+	doc := jsonValue(value)
+	if isPostgres(m.name) {
+		if len(keys) == 0 {
+			return m.WhereRaw(field+" @> "+paramSeat+"::jsonb", doc)
+		}
+		return m.WhereRaw("("+field+" #> "+paramSeat+"::text[]) @> "+paramSeat+"::jsonb", postgresJSONPath(keys), doc)
+	}
+	if len(keys) == 0 {
+		return m.WhereRaw("JSON_CONTAINS("+field+", "+paramSeat+")", doc)
+	}
+	return m.WhereRaw("JSON_CONTAINS("+field+", "+paramSeat+", "+paramSeat+")", doc, mysqlJSONPath(keys))
+}
+
+// UpdateJSON 按当前 where 条件更新 JSON 字段中的一个或多个路径，并返回影响行数。
+//
+// data 的 key 是 JSON 路径，规则与 WhereJSON 一致；value 会先按 JSON 编码后绑定，
+// json.RawMessage 和 []byte 视为已经编码好的 JSON 原文。多个路径按字典序依次写入。
+// MySQL 渲染为 JSON_SET，PostgreSQL 渲染为嵌套 jsonb_set，因此 PostgreSQL 字段应为 jsonb 类型。
+// 字段为 NULL 时会从空对象开始写入，路径中缺少的上级会按下一段是否为数组下标补为空数组或空对象；
+// 已存在但不是对象或数组的上级不会被覆盖，此时该路径不会写入。UpdateJSON 同样要求必须存在 where 条件，
+// 且不能更新 SetEncryption 配置的加密字段。
+//
+// 示例：
+//
+//	rows, err := msql.Model("users").Where("id", "=", "1").
+//	    UpdateJSON("profile", msql.Datas{"address.city": "Hangzhou", "tags.0": "vip"})
+func (m *Builder) UpdateJSON(field string, data Datas) (int64, error) {
+	table, err := m.tableName()
+	if err != nil {
+		return 0, err
+	}
+	field = ToField(field)
+	if field == "" {
+		return 0, errors.New("the field name cannot be empty")
+	}
+	if len(data) < 1 {
+		return 0, errors.New("update data cannot be null")
+	}
+	if err := m.checkEncryptedRaw("UpdateJSON", field); err != nil {
+		return 0, err
+	}
+	where := m.getWhere()
+	if where == "" {
+		return 0, errors.New("where condition cannot be null")
+	}
+	defer m.Reset()
+	expr, values, err := m.jsonSetExpr(field, data)
+	if err != nil {
+		return 0, err
+	}
+	query := "update " + table + " set " + field + " = " + expr + " " + where
+	query = renderParamSeats(m.name, query, 0)
+	whereArgs := m.getWhereArgs()
+	args := append(values, whereArgs...)
+	return m.execAudited(AuditUpdate, where, whereArgs, query, args)
+}
+
+// jsonSetExpr 生成按路径写入 JSON 字段的表达式和按出现顺序排列的绑定参数。
+//
+// JSON_SET 和 jsonb_set 都只会创建路径的最后一段，因此每个路径写入前先补齐缺少的上级：
+// MySQL 使用只在路径不存在时写入的 JSON_INSERT，PostgreSQL 通过子查询复用当前文档并 COALESCE 上级的值。
+func (m *Builder) jsonSetExpr(field string, data Datas) (string, []any, error) {
+	paths := make([]string, 0, len(data))
+	for path := range data {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	postgres := isPostgres(m.name)
+	args := make([]any, 0, len(paths)*2)
+	var expr string
+	if postgres {
+		expr = "COALESCE(" + field + ", '{}'::jsonb)"
+	} else {
+		expr = "COALESCE(" + field + ", JSON_OBJECT())"
+	}
+	for _, path := range paths {
+		keys := splitJSONPath(path)
+		if len(keys) == 0 {
+			return "", nil, fmt.Errorf("invalid json path %q", path)
+		}
+		if postgres {
+			for i := 1; i < len(keys); i++ {
+				parent := postgresJSONPath(keys[:i])
+				expr = "(select jsonb_set(d, " + paramSeat + "::text[], COALESCE(d #> " + paramSeat + "::text[], " +
+					jsonContainer(keys[i], "'[]'::jsonb", "'{}'::jsonb") + "), true) from (select " + expr + " as d) as s)"
+				args = append([]any{parent, parent}, args...)
+			}
+			expr = "jsonb_set(" + expr + ", " + paramSeat + "::text[], " + paramSeat + "::jsonb, true)"
+			args = append(args, postgresJSONPath(keys), jsonValue(data[path]))
+			continue
+		}
+		if len(keys) > 1 {
+			expr = "JSON_INSERT(" + expr
+			for i := 1; i < len(keys); i++ {
+				expr += ", " + paramSeat + ", " + jsonContainer(keys[i], "JSON_ARRAY()", "JSON_OBJECT()")
+				args = append(args, mysqlJSONPath(keys[:i]))
+			}
+			expr += ")"
+		}
+		expr = "JSON_SET(" + expr + ", " + paramSeat + ", CAST(" + paramSeat + " AS JSON))"
+		args = append(args, mysqlJSONPath(keys), jsonValue(data[path]))
+	}
+	return expr, args, nil
+}
+
+// jsonContainer 按下一段路径是否为数组下标返回补齐上级使用的空数组或空对象表达式。
+func jsonContainer(next, array, object string) string {
+	if isJSONIndex(next) {
+		return array
+	}
+	return object
+}
+
+// splitJSONPath 将 JSON 路径拆分为键和数组下标。
+//
+// 支持 a.b.c、$.a.b、tags[0] 和 tags.0 等写法，空段会被忽略。
+func splitJSONPath(path string) []string {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	keys := make([]string, 0)
+	for _, key := range strings.Split(path, ".") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// mysqlJSONPath 将路径键渲染为 MySQL JSON 路径，普通键统一使用双引号包裹。
+func mysqlJSONPath(keys []string) string {
+	var builder strings.Builder
+	builder.WriteString("$")
+	for _, key := range keys {
+		if isJSONIndex(key) {
+			builder.WriteString("[" + key + "]")
+			continue
+		}
+		builder.WriteString(`."` + escapeJSONPathKey(key) + `"`)
+	}
+	return builder.String()
+}
+
+// postgresJSONPath 将路径键渲染为 PostgreSQL text[] 数组字面量。
+func postgresJSONPath(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = `"` + escapeJSONPathKey(key) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// escapeJSONPathKey 转义路径键中的反斜杠和双引号。
+func escapeJSONPathKey(key string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key)
+}
+
+// isJSONIndex 判断路径段是否为数组下标。
+func isJSONIndex(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if !isDigit(key[i]) {
+			return false
+		}
+	}
+	return key != ""
+}
+
+// isJSONNumber 判断比较值是否为数值类型。
+func isJSONNumber(value any) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// jsonValue 将值编码为 JSON 字符串参数。
+//
+// json.RawMessage 和 []byte 视为 JSON 原文；无法编码的值会按 fmt.Sprint 结果编码为 JSON 字符串。
+func jsonValue(value any) string {
+	switch v := value.(type) {
+	case json.RawMessage:
+		return string(v)
+	case []byte:
+		return string(v)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return string(b)
+}
@@ -0,0 +1,162 @@
+package msql
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitJSONPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"", []string{}},
+		{"$", []string{}},
+		{"profile.city", []string{"profile", "city"}},
+		{"$.profile.city", []string{"profile", "city"}},
+		{"tags[0]", []string{"tags", "0"}},
+		{"$.tags[0].name", []string{"tags", "0", "name"}},
+		{" a..b. ", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitJSONPath(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitJSONPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPathRender(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		mysql    string
+		postgres string
+	}{
+		{[]string{"profile", "city"}, `$."profile"."city"`, `{"profile","city"}`},
+		{[]string{"tags", "0"}, `$."tags"[0]`, `{"tags","0"}`},
+		{[]string{`a"b`, `c\d`}, `$."a\"b"."c\\d"`, `{"a\"b","c\\d"}`},
+	}
+	for _, tt := range tests {
+		if got := mysqlJSONPath(tt.keys); got != tt.mysql {
+			t.Errorf("mysqlJSONPath(%q) = %s, want %s", tt.keys, got, tt.mysql)
+		}
+		if got := postgresJSONPath(tt.keys); got != tt.postgres {
+			t.Errorf("postgresJSONPath(%q) = %s, want %s", tt.keys, got, tt.postgres)
+		}
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{"vip", `"vip"`},
+		{[]string{"a", "b"}, `["a","b"]`},
+		{json.RawMessage(`{"a":1}`), `{"a":1}`},
+		{[]byte(`[1,2]`), `[1,2]`},
+		{10, `10`},
+		{make(chan int), ""},
+	}
+	for _, tt := range tests {
+		got := jsonValue(tt.value)
+		if tt.want == "" {
+			if !json.Valid([]byte(got)) {
+				t.Errorf("jsonValue(%T) = %s, want a valid JSON string", tt.value, got)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("jsonValue(%v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWhereJSON(t *testing.T) {
+	tests := []struct {
+		driver   string
+		path     string
+		operator string
+		value    any
+		want     string
+		args     []any
+	}{
+		{
+			DriverMysql, "address.city", "=", "Hangzhou",
+			`select * from users where JSON_UNQUOTE(JSON_EXTRACT(profile, ?)) = ?`,
+			[]any{`$."address"."city"`, "Hangzhou"},
+		},
+		{
+			DriverMysql, "tags[0]", "like", "vi",
+			`select * from users where JSON_UNQUOTE(JSON_EXTRACT(profile, ?)) like ?`,
+			[]any{`$."tags"[0]`, "%vi%"},
+		},
+		{
+			DriverPostgres, "quota", ">", 100,
+			`select * from users where (profile #>> $1::text[])::numeric > $2`,
+			[]any{`{"quota"}`, 100},
+		},
+		{
+			DriverPostgres, "active", "=", true,
+			`select * from users where (profile #>> $1::text[]) = $2`,
+			[]any{`{"active"}`, "true"},
+		},
+		{
+			DriverMysql, "a", "between", 1,
+			`select * from users`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver+"_"+tt.path, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, tt.driver)
+			if _, err := Model("users", name).WhereJSON("profile", tt.path, tt.operator, tt.value).Select(); err != nil {
+				t.Fatalf("Select() error: %v", err)
+			}
+			q, _ := fake.LastQuery()
+			if q.Query != tt.want {
+				t.Errorf("query = %q, want %q", q.Query, tt.want)
+			}
+			if len(q.Args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(q.Args, tt.args) {
+					t.Errorf("args = %#v, want %#v", q.Args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateJSONFillsParents(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+		args   []any
+	}{
+		{
+			DriverMysql,
+			"update users set profile = JSON_SET(JSON_INSERT(COALESCE(profile, JSON_OBJECT()), ?, JSON_ARRAY()), ?, CAST(? AS JSON)) where id=?",
+			[]any{`$."tags"`, `$."tags"[0]`, `"vip"`, "1"},
+		},
+		{
+			DriverPostgres,
+			"update users set profile = jsonb_set((select jsonb_set(d, $1::text[], COALESCE(d #> $2::text[], '[]'::jsonb), true) " +
+				"from (select COALESCE(profile, '{}'::jsonb) as d) as s), $3::text[], $4::jsonb, true) where id=$5",
+			[]any{`{"tags"}`, `{"tags"}`, `{"tags","0"}`, `"vip"`, "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, tt.driver)
+			if _, err := Model("users", name).Where("id", "=", "1").UpdateJSON("profile", Datas{"tags.0": "vip"}); err != nil {
+				t.Fatalf("UpdateJSON() error: %v", err)
+			}
+			q, _ := fake.LastQuery()
+			if q.Query != tt.want {
+				t.Errorf("query = %q\nwant    %q", q.Query, tt.want)
+			}
+			if !reflect.DeepEqual(q.Args, tt.args) {
+				t.Errorf("args = %#v, want %#v", q.Args, tt.args)
+			}
+		})
+	}
+}
@@ -34,6 +34,9 @@ func Model(table string, name ...string) *Builder {
 //
 // Select、Find、Insert、Update、Delete 等执行方法通常会在执行后自动调用 Reset。
 func (m *Builder) Reset() {
+	m.with = nil
+	m.withArgs = nil
+	m.recursive = false
 	m.field = nil
 	m.fieldArgs = nil
 	m.alias = ""
@@ -536,8 +539,10 @@ func (m *Builder) ColumnMap(field, key string) (list Column, err error) {
 
 // Insert 插入一行数据，并返回记录 ID。
 //
-// data 的 key 是字段名，value 是字段值；MySQL 返回数据库生成的自增 ID。
+// data 的 key 是字段名，value 是字段值；value 为 Raw 表达式时会原样拼接并绑定其参数。
+// MySQL 返回数据库生成的自增 ID。
 // PostgreSQL 可通过 returning 指定 ID 字段名，并返回该字段对应的数值。
+// 表通过 SetIDGenerator 开启主键自动生成且 data 中没有主键时，返回生成的 ID。
 //
 // 示例：
 //
@@ -551,49 +556,50 @@ func (m *Builder) Insert(data Datas, returning ...string) (int64, error) {
 	if len(data) < 1 {
 		return 0, errors.New("insert data cannot be null")
 	}
+	data, generated, err := m.generateID(data)
+	if err != nil {
+		return 0, err
+	}
+	if data, err = m.encryptDatas(data); err != nil {
+		return 0, err
+	}
 	m.lastid = 0
 	defer m.Reset()
 	fields := make([]string, len(data))
 	seats := make([]string, len(data))
-	values := make([]any, len(data))
+	values := make([]any, 0, len(data))
 	for index, k := range sortedDataKeys(data) {
+		var args []any
 		fields[index] = ToField(k)
-		seats[index] = getSeatStr(m.name, index)
-		values[index] = data[k]
+		seats[index], args = dataValueSeat(data[k])
+		values = append(values, args...)
 	}
 	query := "insert into " + table + " (" + strings.Join(fields, ", ") +
 		") values (" + strings.Join(seats, ", ") + ")"
 	if len(returning) > 0 { // 兼容 PostgreSQL returning。
 		query += fmt.Sprintf(` RETURNING %s`, strings.Join(returning, `,`))
 	}
+	query = renderParamSeats(m.name, query, 0)
 	m.lastsql = renderDebugParamSeats(query, values)
-	if len(returning) > 0 { // 兼容 PostgreSQL returning。
-		if vs, err := RawValues(m.name, query, m.tx, values...); err == nil {
-			if len(vs) > 0 {
-				m.lastid, _ = strconv.ParseInt(vs[0][returning[0]], 10, 64)
-			}
-			return m.lastid, nil
-		} else {
-			return 0, err
-		}
+	if m.recordDryRun(query, values) {
+		return 0, nil
 	}
-	if ret, err := RawExec(m.name, query, m.tx, values...); err == nil {
-		if isPostgres(m.name) {
-			return 0, nil
-		}
-		id, err := ret.LastInsertId()
-		if err != nil {
-			return 0, err
-		}
-		m.lastid = id
-		return id, nil
+	var id int64
+	if audit := m.auditConfig(); audit != nil {
+		id, err = m.insertAudited(audit, data, query, values, returning)
 	} else {
-		return 0, err
+		id, err = m.execInsert(query, values, returning)
+	}
+	if err != nil || generated == 0 {
+		return id, err
 	}
+	m.lastid = generated
+	return generated, nil
 }
 
 // Update 按当前 where 条件更新数据，并返回影响行数。
 //
+// data 中 value 为 Raw 表达式时会原样拼接并绑定其参数，可与普通字段混合使用。
 // Update 要求必须存在 where 条件，避免误更新整表。
 //
 // 示例：
@@ -601,6 +607,9 @@ func (m *Builder) Insert(data Datas, returning ...string) (int64, error) {
 //	rows, err := msql.Model("users").
 //	    Where("id", "=", "1").
 //	    Update(msql.Datas{"name": "tom"})
+//	rows, err = msql.Model("users").
+//	    Where("id", "=", "1").
+//	    Update(msql.Datas{"name": "tom", "login_count": msql.Raw("login_count + ?", 1)})
 func (m *Builder) Update(data Datas) (int64, error) {
 	table, err := m.tableName()
 	if err != nil {
@@ -614,18 +623,46 @@ func (m *Builder) Update(data Datas) (int64, error) {
 		return 0, errors.New("where condition cannot be null")
 	}
 	defer m.Reset()
+	if data, err = m.encryptDatas(data); err != nil {
+		return 0, err
+	}
 	fields := make([]string, len(data))
-	values := make([]any, len(data))
+	values := make([]any, 0, len(data))
 	for index, k := range sortedDataKeys(data) {
-		fields[index] = ToField(k) + " = " + getSeatStr(m.name, index)
-		values[index] = data[k]
+		seat, args := dataValueSeat(data[k])
+		fields[index] = ToField(k) + " = " + seat
+		values = append(values, args...)
 	}
 	query := "update " + table + " set " +
 		strings.Join(fields, ", ") + " " + where
 	query = renderParamSeats(m.name, query, 0)
 	whereArgs := m.getWhereArgs()
 	args := append(values, whereArgs...)
-	return m.execRowsAffected(query, args)
+	return m.execAudited(AuditUpdate, where, whereArgs, query, args)
+}
+
+// Increment 按当前 where 条件将字段增加 step，并返回影响行数。
+//
+// data 可传入需要同时更新的其它字段；step 会按绑定参数传递。Increment 同样要求必须存在 where 条件。
+//
+// 示例：
+//
+//	rows, err := msql.Model("users").Where("id", "=", "1").Increment("login_count", 1)
+//	rows, err = msql.Model("users").Where("id", "=", "1").
+//	    Increment("login_count", 1, msql.Datas{"last_login": time.Now()})
+func (m *Builder) Increment(field string, step any, data ...Datas) (int64, error) {
+	return m.incrementBy(field, "+", step, data)
+}
+
+// Decrement 按当前 where 条件将字段减少 step，并返回影响行数。
+//
+// 参数规则与 Increment 一致。
+//
+// 示例：
+//
+//	rows, err := msql.Model("goods").Where("id", "=", "1").Decrement("stock", 2)
+func (m *Builder) Decrement(field string, step any, data ...Datas) (int64, error) {
+	return m.incrementBy(field, "-", step, data)
 }
 
 // Update2 使用原始 set SQL 片段按当前 where 条件更新数据，并返回影响行数。
@@ -634,7 +671,7 @@ func (m *Builder) Update(data Datas) (int64, error) {
 // PostgreSQL raw 片段需要使用 $1、$2 等占位符；? 会按 SQL 原文保留，可用于 JSONB 运算符。
 // $n 仅表示一个待绑定参数位置，不表示参数复用；每出现一个 $n 就必须按出现顺序传入一个有实际意义的参数。
 // 构造可执行 SQL 时包内会按最终 SQL 出现顺序重新编号这些 PostgreSQL 占位符。
-// Update2 同样要求必须存在 where 条件。
+// Update2 同样要求必须存在 where 条件；表开启了 SetEncryption 时，片段中不能出现加密字段名。
 //
 // 示例：
 //
@@ -649,6 +686,9 @@ func (m *Builder) Update2(sqlraw string, args ...any) (int64, error) {
 	if sqlraw == "" {
 		return 0, errors.New("update data cannot be null")
 	}
+	if err := m.checkEncryptedRaw("Update2", sqlraw); err != nil {
+		return 0, err
+	}
 	where := m.getWhere()
 	if where == "" {
 		return 0, errors.New("where condition cannot be null")
@@ -660,7 +700,7 @@ func (m *Builder) Update2(sqlraw string, args ...any) (int64, error) {
 	execArgs := make([]any, 0, len(args)+len(whereArgs))
 	execArgs = append(execArgs, args...)
 	execArgs = append(execArgs, whereArgs...)
-	return m.execRowsAffected(query, execArgs)
+	return m.execAudited(AuditUpdate, where, whereArgs, query, execArgs)
 }
 
 // Delete 按当前 where 条件删除数据，并返回影响行数。
@@ -683,14 +723,14 @@ func (m *Builder) Delete() (int64, error) {
 	query := "delete from " + table + " " + where
 	query = renderParamSeats(m.name, query, 0)
 	args := m.getWhereArgs()
-	return m.execRowsAffected(query, args)
+	return m.execAudited(AuditDelete, where, args, query, args)
 }
 
 // TableExists 判断当前 Builder 指定的表是否存在。
 //
-// MySQL 使用 show tables like 查询，PostgreSQL 使用当前 schema 下的 information_schema。
+// MySQL 使用 show tables like 查询，PostgreSQL 使用当前 schema 或 Schema 指定 schema 下的 information_schema。
 func (m *Builder) TableExists() (bool, error) {
-	tableName, err := m.tableName()
+	schema, tableName, err := m.schemaTable()
 	if err != nil {
 		return false, err
 	}
@@ -702,13 +742,17 @@ func (m *Builder) TableExists() (bool, error) {
 	)
 	switch {
 	case isPostgres(m.name):
-		query = "select table_name from information_schema.tables where table_schema = current_schema() and table_name = " + getSeatStr(m.name, 0) + " limit 1"
-		args = []any{tableName}
+		cond, schemaArgs := schemaCondition("table_schema", schema)
+		query = renderParamSeats(m.name, "select table_name from information_schema.tables where "+cond+" and table_name = "+paramSeat+" limit 1", 0)
+		args = append(schemaArgs, tableName)
 		exists = func(vs []Params) bool {
 			return len(vs) == 1
 		}
 	default:
 		query = "show tables like " + quoteSQLValueString(tableName)
+		if schema != "" {
+			query = "show tables from " + schema + " like " + quoteSQLValueString(tableName)
+		}
 		exists = func(vs []Params) bool {
 			if len(vs) == 1 && len(vs[0]) == 1 {
 				for _, t := range vs[0] {
@@ -730,9 +774,9 @@ func (m *Builder) TableExists() (bool, error) {
 
 // FieldExists 判断当前表中指定字段是否存在。
 //
-// MySQL 使用 describe 查询，PostgreSQL 使用当前 schema 下的 information_schema。
+// MySQL 使用 describe 查询，PostgreSQL 使用当前 schema 或 Schema 指定 schema 下的 information_schema。
 func (m *Builder) FieldExists(field string) (bool, error) {
-	table, err := m.tableName()
+	schema, table, err := m.schemaTable()
 	if err != nil {
 		return false, err
 	}
@@ -748,12 +792,14 @@ func (m *Builder) FieldExists(field string) (bool, error) {
 	)
 	switch {
 	case isPostgres(m.name):
-		query = "select column_name from information_schema.columns where table_schema = current_schema() and table_name = " + getSeatStr(m.name, 0) + " and column_name = " + getSeatStr(m.name, 1) + " limit 1"
-		args = []any{table, field}
+		cond, schemaArgs := schemaCondition("table_schema", schema)
+		query = renderParamSeats(m.name, "select column_name from information_schema.columns where "+cond+" and table_name = "+paramSeat+" and column_name = "+paramSeat+" limit 1", 0)
+		args = append(schemaArgs, table, field)
 		exists = func(vs []Params) bool {
 			return len(vs) == 1
 		}
 	default:
+		table, _ = m.tableName()
 		query = "describe " + table + " " + field
 		exists = func(vs []Params) bool {
 			return len(vs) == 1 && ToField(vs[0]["Field"]) == field
@@ -769,9 +815,9 @@ func (m *Builder) FieldExists(field string) (bool, error) {
 
 // IndexExists 判断当前表中指定索引是否存在。
 //
-// MySQL 使用 show index 查询，PostgreSQL 使用当前 schema 下的 pg_indexes。
+// MySQL 使用 show index 查询，PostgreSQL 使用当前 schema 或 Schema 指定 schema 下的 pg_indexes。
 func (m *Builder) IndexExists(keyname string) (bool, error) {
-	table, err := m.tableName()
+	schema, table, err := m.schemaTable()
 	if err != nil {
 		return false, err
 	}
@@ -786,12 +832,14 @@ func (m *Builder) IndexExists(keyname string) (bool, error) {
 	)
 	switch {
 	case isPostgres(m.name):
-		query = "select indexname from pg_indexes where schemaname = current_schema() and tablename = " + getSeatStr(m.name, 0) + " and indexname = " + getSeatStr(m.name, 1) + " limit 1"
-		args = []any{table, keyname}
+		cond, schemaArgs := schemaCondition("schemaname", schema)
+		query = renderParamSeats(m.name, "select indexname from pg_indexes where "+cond+" and tablename = "+paramSeat+" and indexname = "+paramSeat+" limit 1", 0)
+		args = append(schemaArgs, table, keyname)
 		exists = func(vs []Params) bool {
 			return len(vs) == 1
 		}
 	default:
+		table, _ = m.tableName()
 		query = "show index from " + table
 		exists = func(vs []Params) bool {
 			for _, v := range vs {
@@ -812,9 +860,9 @@ func (m *Builder) IndexExists(keyname string) (bool, error) {
 
 // GetFields 查询当前表的字段名列表。
 //
-// MySQL 使用 describe 查询，PostgreSQL 使用当前 schema 下的 information_schema，并按字段顺序返回。
+// MySQL 使用 describe 查询，PostgreSQL 使用当前 schema 或 Schema 指定 schema 下的 information_schema，并按字段顺序返回。
 func (m *Builder) GetFields() ([]string, error) {
-	table, err := m.tableName()
+	schema, table, err := m.schemaTable()
 	if err != nil {
 		return nil, err
 	}
@@ -826,10 +874,12 @@ func (m *Builder) GetFields() ([]string, error) {
 	)
 	switch {
 	case isPostgres(m.name):
-		query = "select column_name as Field from information_schema.columns where table_schema = current_schema() and table_name = " + getSeatStr(m.name, 0) + " order by ordinal_position"
-		args = []any{table}
+		cond, schemaArgs := schemaCondition("table_schema", schema)
+		query = renderParamSeats(m.name, "select column_name as Field from information_schema.columns where "+cond+" and table_name = "+paramSeat+" order by ordinal_position", 0)
+		args = append(schemaArgs, table)
 		fieldKeys = []string{"field", "Field"}
 	default:
+		table, _ = m.tableName()
 		query = "describe " + table
 		fieldKeys = []string{"Field"}
 	}
@@ -865,7 +915,7 @@ func (m *Builder) Begin() error {
 	if m.istx {
 		return TxE1
 	}
-	tx, err := Begin(m.name)
+	tx, err := beginTx(m.context(), m.name)
 	if err == nil {
 		m.istx, m.tx = true, tx
 		logSQLTxBoundary(m.name, m.table, "BEGIN")
@@ -0,0 +1,84 @@
+package msql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpressionUpdates(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		run    func(m *Builder) (int64, error)
+		want   string
+		args   []any
+	}{
+		{
+			"increment", DriverMysql,
+			func(m *Builder) (int64, error) { return m.Increment("login_count", 1) },
+			"update users set login_count = login_count + ? where id=?",
+			[]any{1, "7"},
+		},
+		{
+			"decrement with data", DriverPostgres,
+			func(m *Builder) (int64, error) { return m.Decrement("stock", 2, Datas{"status": "low"}) },
+			"update users set status = $1, stock = stock - $2 where id=$3",
+			[]any{"low", 2, "7"},
+		},
+		{
+			"raw with placeholders", DriverPostgres,
+			func(m *Builder) (int64, error) {
+				return m.Update(Datas{"score": Raw("score * $1 + $2", 2, 1), "name": "tom"})
+			},
+			"update users set name = $1, score = score * $2 + $3 where id=$4",
+			[]any{"tom", 2, 1, "7"},
+		},
+		{
+			"raw keeps question marks on postgres", DriverPostgres,
+			func(m *Builder) (int64, error) { return m.Update(Datas{"has_tag": Raw("tags ? $1", "vip")}) },
+			"update users set has_tag = tags ? $1 where id=$2",
+			[]any{"vip", "7"},
+		},
+		{
+			"update2", DriverPostgres,
+			func(m *Builder) (int64, error) { return m.Update2("score=$1, level=$2", 100, 3) },
+			"update users set score=$1, level=$2 where id=$3",
+			[]any{100, 3, "7"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, fake := newTestFakeDataBase(t, tt.driver)
+			if _, err := tt.run(Model("users", name).Where("id", "=", "7")); err != nil {
+				t.Fatalf("update error: %v", err)
+			}
+			q, _ := fake.LastQuery()
+			if q.Query != tt.want {
+				t.Errorf("query = %q, want %q", q.Query, tt.want)
+			}
+			if !reflect.DeepEqual(q.Args, tt.args) {
+				t.Errorf("args = %#v, want %#v", q.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestUpdatesRequireWhere(t *testing.T) {
+	name, fake := newTestFakeDataBase(t, DriverMysql)
+	tests := []struct {
+		name string
+		run  func(m *Builder) (int64, error)
+	}{
+		{"Update", func(m *Builder) (int64, error) { return m.Update(Datas{"a": 1}) }},
+		{"Increment", func(m *Builder) (int64, error) { return m.Increment("a", 1) }},
+		{"Update2", func(m *Builder) (int64, error) { return m.Update2("a=1") }},
+	}
+	for _, tt := range tests {
+		if _, err := tt.run(Model("users", name)); err == nil || err.Error() != "where condition cannot be null" {
+			t.Errorf("%s without where error = %v", tt.name, err)
+		}
+	}
+	if got := len(fake.Queries()); got != 0 {
+		t.Errorf("sent %d queries, want none", got)
+	}
+}
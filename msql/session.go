@@ -0,0 +1,198 @@
+package msql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// SessionInit 表示数据库别名新建物理连接后执行的会话初始化配置。
+//
+// Statements 会按顺序在每个新连接上执行，随后调用 Func；任一步失败时该连接会被关闭，
+// 本次获取连接的操作返回错误。常见用途包括设置时区、PostgreSQL search_path 和 MySQL sql_mode。
+type SessionInit struct {
+	// Statements 为按顺序执行的初始化 SQL，例如 SET TIME ZONE 'UTC'。
+	Statements []string
+	// Func 为可选的初始化回调，在 Statements 执行完成后调用。
+	Func func(ctx context.Context, conn SessionConn) error
+}
+
+// SessionConn 表示正在初始化的物理连接，只在 SessionInit.Func 回调期间有效。
+type SessionConn interface {
+	// Exec 在当前物理连接上执行 SQL，args 使用驱动原生占位符绑定。
+	Exec(ctx context.Context, query string, args ...any) error
+	// Driver 返回当前连接的驱动名，即 DriverMysql 或 DriverPostgres。
+	Driver() string
+}
+
+// RegisterDataBaseSession 注册数据库连接，并为连接池中每个新建的物理连接执行会话初始化。
+//
+// 初始化通过包装驱动 Connector 实现，对注册时的连通性检查连接同样生效；其余规则与 RegisterDataBase 一致。
+//
+// 示例：
+//
+//	err := msql.RegisterDataBaseSession("pg", conn, msql.SessionInit{
+//	    Statements: []string{"SET TIME ZONE 'UTC'", "SET search_path TO app, public"},
+//	}, msql.DriverPostgres)
+//	err = msql.RegisterDataBaseSession("default", dsn, msql.SessionInit{
+//	    Func: func(ctx context.Context, conn msql.SessionConn) error {
+//	        return conn.Exec(ctx, "SET SESSION sql_mode = ?", "STRICT_ALL_TABLES")
+//	    },
+//	})
+func RegisterDataBaseSession(name, conn string, init SessionInit, driverName ...string) error {
+	return registerDataBase(name, conn, &init, driverName...)
+}
+
+// Schema 设置当前 Builder 使用的 schema，传入空字符串时恢复为连接默认 schema。
+//
+// 设置后查询和写入 SQL 中的表名会带上 schema 前缀；TableExists、FieldExists、IndexExists 和 GetFields
+// 在 PostgreSQL 下按该 schema 查询，而不是 current_schema()，MySQL 下则把 schema 视为数据库名。
+// 表名本身已包含 schema 前缀时不会重复添加。schema 会原样拼接，调用方需保证其可信。
+//
+// 示例：
+//
+//	list, err := msql.Model("orders", "pg").Schema("tenant_01").Where("status", "=", "paid").Select()
+//	exists, err := msql.Model("orders", "pg").Schema("archive").TableExists()
+func (m *Builder) Schema(name string) *Builder {
+	m.schema = ToField(name)
+	return m
+}
+
+// schemaTable 返回表结构查询使用的 schema 和未加 schema 前缀的表名。
+func (m *Builder) schemaTable() (schema, table string, err error) {
+	if m == nil {
+		return "", "", errEmptyTableName
+	}
+	table = ToField(m.table)
+	if table == "" {
+		return "", "", errEmptyTableName
+	}
+	return m.schema, table, nil
+}
+
+// schemaCondition 生成 PostgreSQL 表结构查询的 schema 条件。
+//
+// 未设置 schema 时使用 current_schema()，否则使用内部占位符并返回对应参数。
+func schemaCondition(column, schema string) (string, []any) {
+	if schema == "" {
+		return column + " = current_schema()", nil
+	}
+	return column + " = " + paramSeat, []any{schema}
+}
+
+// sessionConnector 在底层 Connector 新建连接后执行会话初始化。
+type sessionConnector struct {
+	base   driver.Connector
+	driver string
+	init   *SessionInit
+}
+
+// Connect 新建物理连接并执行初始化，初始化失败时关闭连接。
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := &sessionConn{conn: conn, driver: c.driver}
+	for _, stmt := range c.init.Statements {
+		if err := session.Exec(ctx, stmt); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("session init %q: %w", stmt, err)
+		}
+	}
+	if c.init.Func != nil {
+		if err := c.init.Func(ctx, session); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("session init: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// Driver 返回底层驱动。
+func (c *sessionConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// dsnConnector 为未实现 driver.DriverContext 的驱动提供 Connector。
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect 使用 DSN 打开新连接。
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver 返回底层驱动。
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sessionConn 为初始化回调提供在同一物理连接上执行 SQL 的能力。
+type sessionConn struct {
+	conn   driver.Conn
+	driver string
+}
+
+// Exec 优先使用驱动的 ExecerContext 执行 SQL，驱动要求预处理时退回到 Prepare 执行。
+func (c *sessionConn) Exec(ctx context.Context, query string, args ...any) error {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return fmt.Errorf("arg %d: %w", i+1, err)
+		}
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	if execer, ok := c.conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, named)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+	stmt, err := c.conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer func(stmt driver.Stmt) {
+		_ = stmt.Close()
+	}(stmt)
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, named)
+		return err
+	}
+	values := make([]driver.Value, len(named))
+	for i, v := range named {
+		values[i] = v.Value
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
+
+// Driver 返回当前连接的驱动名。
+func (c *sessionConn) Driver() string {
+	return c.driver
+}
+
+// openSessionDB 使用包装后的 Connector 打开连接池。
+//
+// 通过 sql.Open 获取已注册的驱动实例，再按驱动能力创建底层 Connector。
+func openSessionDB(driverName, conn string, init *SessionInit) (*sql.DB, error) {
+	probe, err := sql.Open(driverName, conn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	_ = probe.Close()
+	var base driver.Connector = dsnConnector{dsn: conn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if base, err = dc.OpenConnector(conn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(&sessionConnector{base: base, driver: driverName, init: init}), nil
+}
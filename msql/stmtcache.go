@@ -0,0 +1,256 @@
+package msql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// StmtCacheStats 表示别名预处理语句缓存的统计信息。
+type StmtCacheStats struct {
+	// Size 为当前缓存的语句数量。
+	Size int
+	// Capacity 为缓存容量，0 表示未开启。
+	Capacity int
+	// Hits 为命中缓存的次数。
+	Hits int64
+	// Misses 为未命中并重新预处理的次数。
+	Misses int64
+	// Evictions 为超出容量被淘汰的语句数量。
+	Evictions int64
+}
+
+// SetStmtCache 开启、调整或关闭指定连接的预处理语句 LRU 缓存。
+//
+// size 大于 0 时，RawValues、RawExec 以及基于它们的 Builder 方法会以最终渲染后的 SQL 为 key
+// 复用 *sql.Stmt，事务内通过 tx.Stmt 复用已缓存的语句；超过 size 时淘汰最久未使用的语句，
+// 正在执行的语句会在执行结束后再关闭。size 小于等于 0 时关闭缓存并释放已缓存的语句。
+// 调整容量不会清空统计信息。无法预处理的 SQL 会直接执行，不会进入缓存。
+//
+// 示例：
+//
+//	err := msql.SetStmtCache("", 256)
+//	stats, _ := msql.GetStmtCacheStats("")
+//	fmt.Println(stats.Hits, stats.Misses)
+func SetStmtCache(name string, size int) error {
+	return useDataBaseAlias(name, func(alias *dataBase) {
+		alias.mu.Lock()
+		cache := alias.stmts
+		if cache == nil && size > 0 {
+			cache = &stmtCache{items: make(map[string]*list.Element), order: list.New()}
+			alias.stmts = cache
+		}
+		alias.mu.Unlock()
+		if cache != nil {
+			cache.resize(size)
+		}
+	})
+}
+
+// GetStmtCacheStats 返回指定连接的预处理语句缓存统计信息。
+func GetStmtCacheStats(name string) (StmtCacheStats, error) {
+	alias, err := getDB(name)
+	if err != nil {
+		return StmtCacheStats{}, err
+	}
+	alias.mu.RLock()
+	cache := alias.stmts
+	alias.mu.RUnlock()
+	if cache == nil {
+		return StmtCacheStats{}, nil
+	}
+	return cache.stats(), nil
+}
+
+// stmtCache 是按 SQL 文本索引的预处理语句 LRU 缓存。
+//
+// 每条语句带有引用计数，被淘汰时如果仍在使用，会延迟到最后一次释放时关闭。
+type stmtCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	hits     int64
+	misses   int64
+	evicts   int64
+}
+
+// stmtEntry 表示缓存中的一条预处理语句。
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// acquire 返回 query 对应的缓存语句并增加引用计数；未命中时在 db 上预处理后放入缓存。
+//
+// 返回的 entry 使用后必须调用 release。缓存已关闭或预处理失败时返回错误，调用方应退回直接执行。
+func (c *stmtCache) acquire(ctx context.Context, db *sql.DB, query string) (*stmtEntry, error) {
+	c.mu.Lock()
+	if c.capacity <= 0 {
+		c.mu.Unlock()
+		return nil, errStmtCacheDisabled
+	}
+	if el, ok := c.items[query]; ok {
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.hits++
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[query]; ok {
+		// 并发未命中时保留先放入缓存的语句。
+		_ = stmt.Close()
+		entry := el.Value.(*stmtEntry)
+		entry.refs++
+		c.order.MoveToFront(el)
+		return entry, nil
+	}
+	entry := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	if c.capacity <= 0 {
+		entry.evicted = true
+		return entry, nil
+	}
+	c.items[query] = c.order.PushFront(entry)
+	c.evictLocked()
+	return entry, nil
+}
+
+// lookup 只查找 query 对应的缓存语句并增加引用计数，未命中时不预处理，返回 nil 并计入未命中次数。
+//
+// 事务内使用 lookup：在连接池上预处理需要另一个空闲连接，连接数受限时会与事务互相等待。
+func (c *stmtCache) lookup(query string) (*stmtEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity <= 0 {
+		return nil, errStmtCacheDisabled
+	}
+	el, ok := c.items[query]
+	if !ok {
+		c.misses++
+		return nil, nil
+	}
+	entry := el.Value.(*stmtEntry)
+	entry.refs++
+	c.hits++
+	c.order.MoveToFront(el)
+	return entry, nil
+}
+
+// release 减少引用计数，已被淘汰且不再使用的语句会被关闭。
+func (c *stmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	entry.refs--
+	closable := entry.evicted && entry.refs == 0
+	c.mu.Unlock()
+	if closable {
+		_ = entry.stmt.Close()
+	}
+}
+
+// resize 调整缓存容量并淘汰超出的语句。
+func (c *stmtCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	c.capacity = size
+	c.evictLocked()
+}
+
+// evictLocked 淘汰超出容量的最久未使用语句，调用方需持有 c.mu。
+func (c *stmtCache) evictLocked() {
+	for c.order.Len() > c.capacity {
+		el := c.order.Back()
+		entry := el.Value.(*stmtEntry)
+		c.order.Remove(el)
+		delete(c.items, entry.query)
+		entry.evicted = true
+		c.evicts++
+		if entry.refs == 0 {
+			_ = entry.stmt.Close()
+		}
+	}
+}
+
+// close 关闭缓存并释放全部语句，正在执行的语句会在执行结束后再关闭。
+func (c *stmtCache) close() {
+	c.resize(0)
+}
+
+// stats 返回缓存统计信息快照。
+func (c *stmtCache) stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return StmtCacheStats{
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evicts,
+	}
+}
+
+// cachedStmt 返回 query 可用的语句和释放函数。
+//
+// 别名未开启缓存或 SQL 无法预处理时返回 nil，调用方应直接使用 db 或 tx 执行。
+// tx 不为空时，命中缓存返回 tx.Stmt 包装的语句，database/sql 会在事务连接上复用或重新预处理；
+// 未命中时在事务上临时预处理，语句在释放时关闭，不会放入缓存。
+func cachedStmt(ctx context.Context, name string, db *sql.DB, tx *sql.Tx, query string) (*sql.Stmt, func()) {
+	alias, ok := lookupDataBase(name)
+	if !ok || alias == nil {
+		return nil, nil
+	}
+	alias.mu.RLock()
+	cache := alias.stmts
+	alias.mu.RUnlock()
+	if cache == nil {
+		return nil, nil
+	}
+	if tx != nil {
+		return cachedTxStmt(ctx, cache, tx, query)
+	}
+	entry, err := cache.acquire(ctx, db, query)
+	if err != nil {
+		return nil, nil
+	}
+	return entry.stmt, func() {
+		cache.release(entry)
+	}
+}
+
+// cachedTxStmt 返回事务内使用的语句，释放时关闭事务语句并归还缓存引用。
+func cachedTxStmt(ctx context.Context, cache *stmtCache, tx *sql.Tx, query string) (*sql.Stmt, func()) {
+	entry, err := cache.lookup(query)
+	if err != nil {
+		return nil, nil
+	}
+	if entry == nil {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, nil
+		}
+		return stmt, func() {
+			_ = stmt.Close()
+		}
+	}
+	stmt := tx.StmtContext(ctx, entry.stmt)
+	return stmt, func() {
+		_ = stmt.Close()
+		cache.release(entry)
+	}
+}
@@ -0,0 +1,61 @@
+package msql
+
+import "testing"
+
+func TestStmtCache(t *testing.T) {
+	name, _ := newTestFakeDataBase(t, DriverMysql)
+	if err := SetStmtCache(name, 2); err != nil {
+		t.Fatalf("SetStmtCache() error: %v", err)
+	}
+	find := func(table string) {
+		t.Helper()
+		if _, err := Model(table, name).Where("id", "=", "1").Find(); err != nil {
+			t.Fatalf("Find() on %s error: %v", table, err)
+		}
+	}
+	tests := []struct {
+		table string
+		want  StmtCacheStats
+	}{
+		{"users", StmtCacheStats{Size: 1, Capacity: 2, Misses: 1}},
+		{"users", StmtCacheStats{Size: 1, Capacity: 2, Hits: 1, Misses: 1}},
+		{"orders", StmtCacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 2}},
+		{"items", StmtCacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 3, Evictions: 1}},
+		{"users", StmtCacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 4, Evictions: 2}},
+	}
+	for i, tt := range tests {
+		find(tt.table)
+		got, err := GetStmtCacheStats(name)
+		if err != nil {
+			t.Fatalf("GetStmtCacheStats() error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("step %d (%s): stats = %+v, want %+v", i, tt.table, got, tt.want)
+		}
+	}
+
+	m := Model("users", name)
+	if err := m.Begin(); err != nil {
+		t.Fatalf("Begin() error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := m.Where("id", "=", "1").Find(); err != nil {
+			t.Fatalf("Find() in a transaction error: %v", err)
+		}
+	}
+	if err := m.Commit(); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	got, _ := GetStmtCacheStats(name)
+	if got.Hits != 3 || got.Misses != 4 {
+		t.Errorf("stats after the transaction = %+v, want 3 hits and 4 misses", got)
+	}
+
+	if err := SetStmtCache(name, 0); err != nil {
+		t.Fatalf("SetStmtCache(0) error: %v", err)
+	}
+	find("users")
+	if got, _ := GetStmtCacheStats(name); got.Size != 0 || got.Capacity != 0 {
+		t.Errorf("stats after disabling = %+v, want an empty cache", got)
+	}
+}
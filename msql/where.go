@@ -16,7 +16,7 @@ import "strings"
 // 需要在原始 SQL 条件中绑定参数时，请使用 WhereRaw。
 // PostgreSQL 三段式条件由包内自动渲染占位符；单参数原始 SQL 条件不接收绑定参数。
 func (m *Builder) Where(a ...string) *Builder {
-	where, args := toWhere(a)
+	where, args := m.toWhere(a)
 	if where == "" {
 		return m
 	}
@@ -56,7 +56,7 @@ func (m *Builder) WhereRaw(query string, args ...any) *Builder {
 // 需要在原始 SQL 条件中绑定参数时，请使用 WhereOrRaw。
 // PostgreSQL 三段式条件由包内自动渲染占位符；单参数原始 SQL 条件不接收绑定参数。
 func (m *Builder) WhereOr(a ...string) *Builder {
-	whereor, args := toWhere(a)
+	whereor, args := m.toWhere(a)
 	if whereor == "" {
 		return m
 	}
@@ -199,6 +199,14 @@ func (m *Builder) WhereOr2(l [][]string) *Builder {
 	return m
 }
 
+// toWhere 转换 Where/WhereOr 条件，加密字段上的等值条件会先改写为密文或盲索引匹配。
+func (m *Builder) toWhere(a []string) (string, []any) {
+	if where, args, ok := m.encryptedWhere(a); ok {
+		return where, args
+	}
+	return toWhere(a)
+}
+
 // toWhere 将 Where/WhereOr 的字符串参数转换为 SQL 条件和绑定参数。
 //
 // 这是旧字符串式 Where API 的兼容转换逻辑；新的类型化条件优先使用 WhereIn、WhereLike 等方法。
@@ -314,6 +322,9 @@ func (m *Builder) whereIn(field, operator string, values []any) *Builder {
 	if field == "" || len(values) == 0 {
 		return m
 	}
+	if where, args, ok := m.encryptedCondition(field, operator, values); ok {
+		return m.WhereRaw(where, args...)
+	}
 	seats := make([]string, len(values))
 	for i := range values {
 		seats[i] = paramSeat